@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/draxil/json2nd/internal/json"
+	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
+)
+
+// branch is one of the paths we're following, when there are several we
+// follow them all in the same pass through the JSON.
+type branch struct {
+	nodes path.Path
+	out   io.Writer
+	tag   bool
+	// context is set for the branches which find a -context value
+	context *contextValue
+}
+
+// branches works out what paths we've been asked to follow, and where each
+// should go.
+func (p processor) branches() ([]branch, error) {
+	if p.options.Find != "" {
+		nodes := path.Path{{Kind: path.Descendant, Key: p.options.Find}}
+		return []branch{{nodes: nodes, out: p.out}}, nil
+	}
+
+	if p.options.Pointer != "" {
+		nodes, err := path.ParsePointer(p.options.Pointer)
+		if err != nil {
+			return nil, err
+		}
+		return []branch{{nodes: nodes, out: p.out}}, nil
+	}
+
+	branches := make([]branch, 0, len(p.options.Paths))
+	names := make([]string, 0, len(p.options.Paths))
+	shared := map[string]int{}
+
+	for _, spec := range p.options.Paths {
+		nodes, name, err := path.ParseTarget(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		out := p.out
+		if name != "" {
+			var ok bool
+			out, ok = p.outputs[name]
+			if !ok {
+				return nil, errNoOutput(name)
+			}
+		}
+
+		branches = append(branches, branch{nodes: nodes, out: out})
+		names = append(names, name)
+		shared[name]++
+	}
+
+	// where paths share an output we need to be able to tell their
+	// records apart:
+	for i, name := range names {
+		branches[i].tag = shared[name] > 1
+	}
+
+	return append(branches, p.contexts.branches()...), nil
+}
+
+// handleBranches follows several paths at once from the value under the
+// cursor, see handlePathNodes.
+func (p processor) handleBranches(branches []branch, scan *json.JSON) error {
+	if len(branches) == 1 {
+		return p.onBranch(branches[0]).handlePathNodes(branches[0].nodes, scan)
+	}
+
+	for _, b := range branches {
+		if len(b.nodes) == 0 {
+			return errOverlappingPaths(p.atString())
+		}
+	}
+
+	clue, err := scan.Next()
+	if err != nil {
+		return err
+	}
+
+	switch clue {
+	case '{':
+		return p.handleBranchesObject(branches, scan)
+	case '[':
+		return p.handleBranchesArray(branches, scan)
+	}
+
+	// nowhere to go from here, let the first path report the problem:
+	return p.handleBranches(branches[:1], scan)
+}
+
+func (p processor) handleBranchesObject(branches []branch, scan *json.JSON) error {
+	it, err := scan.IterObject()
+	if err != nil {
+		return err
+	}
+
+	found := make([]bool, len(branches))
+	var last []lastValue
+
+	for {
+		if allFound(found) && !p.duplicatesMatter() {
+			return scan.SkipRest('{')
+		}
+
+		more, err := it.Next()
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		key := string(it.Key())
+		var next, keyed []branch
+
+		for i, b := range branches {
+			step := b.nodes[0]
+			switch step.Kind {
+			case path.Key, path.Token:
+				if step.Key != key {
+					continue
+				}
+				if found[i] && p.options.DuplicateKeys == options.DuplicateError {
+					return errDuplicateKey(p.down(step).where())
+				}
+				if found[i] && !p.duplicatesMatter() {
+					continue
+				}
+				found[i] = true
+				if p.options.DuplicateKeys == options.DuplicateLast {
+					keyed = append(keyed, b.follow())
+				} else {
+					next = append(next, b.follow())
+				}
+			case path.Wildcard:
+				next = append(next, b.follow())
+			case path.Descendant:
+				if step.Key == key {
+					next = append(next, b.follow())
+				} else {
+					next = append(next, b)
+				}
+			}
+		}
+
+		at := p.down(path.Step{Kind: path.Key, Key: key})
+
+		if len(keyed) > 0 {
+			// hang on to the value for the keyed branches until we know
+			// it's the last one, the rest can have it now:
+			value := &bytes.Buffer{}
+			_, err = scan.WriteCurrentTo(value, true)
+			if err != nil {
+				return err
+			}
+			last = setLastValue(last, key, value.Bytes(), keyed)
+			if len(next) > 0 {
+				err = at.handleBranches(next, json.New(bytes.NewReader(value.Bytes())))
+			}
+		} else if len(next) == 0 {
+			err = scan.Skip()
+		} else {
+			err = at.handleBranches(next, scan)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, l := range last {
+		at := p.down(path.Step{Kind: path.Key, Key: l.key})
+		err := at.handleBranches(l.branches, json.New(bytes.NewReader(l.value)))
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.missingBranches(branches, found)
+}
+
+// lastValue is the latest value we've seen for a key which some branches
+// lead through, for -duplicate-keys=last.
+type lastValue struct {
+	key      string
+	value    []byte
+	branches []branch
+}
+
+func setLastValue(last []lastValue, key string, value []byte, branches []branch) []lastValue {
+	for i := range last {
+		if last[i].key == key {
+			last[i].value = value
+			return last
+		}
+	}
+	return append(last, lastValue{key: key, value: value, branches: branches})
+}
+
+func (p processor) handleBranchesArray(branches []branch, scan *json.JSON) error {
+	it, err := scan.IterArray()
+	if err != nil {
+		return err
+	}
+
+	found := make([]bool, len(branches))
+
+	for {
+		if allFound(found) {
+			return scan.SkipRest('[')
+		}
+
+		more, err := it.Next()
+		if err != nil {
+			return err
+		}
+		if !more {
+			break
+		}
+
+		i := it.Index()
+		var next []branch
+
+		for bi, b := range branches {
+			step := b.nodes[0]
+			switch step.Kind {
+			case path.Index, path.Token:
+				if step.Index == i {
+					found[bi] = true
+					next = append(next, b.follow())
+				}
+			case path.Slice:
+				if i >= step.Index && (step.End < 0 || i < step.End) {
+					next = append(next, b.follow())
+				}
+				// a slice is "found" once we're past the end of it:
+				found[bi] = step.End >= 0 && i+1 >= step.End
+			case path.Wildcard:
+				next = append(next, b.follow())
+			case path.Descendant:
+				next = append(next, b)
+			}
+		}
+
+		if len(next) == 0 {
+			err = scan.Skip()
+		} else {
+			err = p.downIndex(i).handleBranches(next, scan)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return p.missingBranches(branches, found)
+}
+
+// onBranch gives us a processor which outputs where the branch should.
+func (p processor) onBranch(b branch) processor {
+	p.out = b.out
+	p.tag = b.tag
+	p.context = b.context
+	return p
+}
+
+// follow moves the branch on to its next node.
+func (b branch) follow() branch {
+	b.nodes = b.nodes[1:]
+	return b
+}
+
+// allFound checks if there's nothing left in an object or array for the
+// branches to find. Wildcards and searches are never done.
+func allFound(found []bool) bool {
+	for _, f := range found {
+		if !f {
+			return false
+		}
+	}
+	return true
+}
+
+// missingBranches deals with the branches that were looking for a particular
+// key or index and didn't find it.
+func (p processor) missingBranches(branches []branch, found []bool) error {
+	for i, b := range branches {
+		if found[i] {
+			continue
+		}
+
+		switch b.nodes[0].Kind {
+		case path.Key, path.Index, path.Token:
+			err := p.onBranch(b).missing(b.nodes[0])
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func errOverlappingPaths(at string) error {
+	return optionError{fmt.Errorf("paths overlap at (%s), one path can't lead into what another extracts", at)}
+}
+
+func errNoOutput(name string) error {
+	return optionError{fmt.Errorf("no output open for: %s", name)}
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/draxil/json2nd/internal/json"
+	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
+)
+
+// contexts are the values -context picks up on the way to what -path
+// extracts, to add to each record. They're found in the same pass as
+// everything else so they have to come before the records in the input.
+type contexts struct {
+	values []*contextValue
+	// written is set once we've written a record, after which it's too late
+	// to find any more values
+	written bool
+}
+
+// contextValue is one -context, value is nil until we find it.
+type contextValue struct {
+	nodes path.Path
+	src   string
+	// name is the field it goes in the records as
+	name  string
+	value []byte
+}
+
+func newContexts(specs []string) (*contexts, error) {
+	c := &contexts{}
+	for _, spec := range specs {
+		nodes, name, err := path.ParseTarget(spec)
+		if err != nil {
+			return nil, err
+		}
+		if name == "" {
+			name = nodes.String()
+		}
+		c.values = append(c.values, &contextValue{nodes: nodes, src: nodes.String(), name: name})
+	}
+	return c, nil
+}
+
+// reset forgets what we've found, for the next value in a stream.
+func (c *contexts) reset() {
+	if c == nil {
+		return
+	}
+	for _, v := range c.values {
+		v.value = nil
+	}
+	c.written = false
+}
+
+// branches to follow to find the contexts, alongside the -path ones.
+func (c *contexts) branches() []branch {
+	if c == nil {
+		return nil
+	}
+
+	branches := make([]branch, 0, len(c.values))
+	for _, v := range c.values {
+		branches = append(branches, branch{nodes: v.nodes, out: io.Discard, context: v})
+	}
+	return branches
+}
+
+// captureContext holds on to the value under the cursor for the context
+// we're looking for.
+func (p processor) captureContext(scan *json.JSON) error {
+	if p.contexts.written {
+		return errLateContext(p.context.src, p.where())
+	}
+
+	clue, err := scan.Next()
+	if err != nil {
+		return err
+	}
+	if !json.SaneValueStart(clue) {
+		return errPathLeadToBadValue(clue, p.where())
+	}
+
+	value := bytes.Buffer{}
+	_, err = scan.WriteCurrentTo(&value, true)
+	if err != nil {
+		return err
+	}
+	p.context.value = value.Bytes()
+	return nil
+}
+
+// missingContext deals with a context that doesn't exist, like missing
+// does for a path. With emit-null or default it's left for
+// writeWithContext to fill in.
+func (p processor) missingContext(node path.Step) error {
+	switch p.options.Missing {
+	case options.MissingSkip:
+		return p.skipped(errBadPath(node.String(), p.where()))
+	case options.MissingNull, options.MissingDefault:
+		return nil
+	}
+	return errBadPath(node.String(), p.where())
+}
+
+// missingValue is what a context we haven't found is taken to be, nil for
+// nothing at all. We may not know it's missing until after the records, so
+// emit-null and default apply to any we haven't found yet.
+func (p processor) missingValue() []byte {
+	switch p.options.Missing {
+	case options.MissingNull:
+		return []byte("null")
+	case options.MissingDefault:
+		return []byte(p.options.MissingDefault)
+	}
+	return nil
+}
+
+// contextsFound checks we have the contexts for the record we're about to
+// write, w is where it is. Unless -missing says what to do without one, a
+// context we haven't found yet is an error, as it's either not there or
+// it's after the records.
+func (p processor) contextsFound(w json.Where) error {
+	if p.contexts == nil || p.options.Missing == options.MissingSkip || p.missingValue() != nil {
+		return nil
+	}
+
+	for _, v := range p.contexts.values {
+		if v.value == nil {
+			return errContextNotFound(v.src, w)
+		}
+	}
+	return nil
+}
+
+// writeWithContext writes out the value under the cursor with the contexts
+// we've found added to it. If it's an object they go in alongside its
+// fields, in place of any of the same name, otherwise it goes in a "value"
+// field next to them.
+func (p processor) writeWithContext(out io.Writer, js *json.JSON) (int, error) {
+	p.contexts.written = true
+
+	head := &memberWriter{}
+	names := map[string]bool{}
+	for _, v := range p.contexts.values {
+		value := v.value
+		if value == nil {
+			value = p.missingValue()
+		}
+		if value != nil {
+			head.write(member{key: v.name, value: value})
+			names[v.name] = true
+		}
+	}
+	if head.err != nil {
+		return 0, head.err
+	}
+	if head.b.Len() == 0 {
+		return js.WriteCurrentTo(out, true)
+	}
+
+	clue := js.Peek()
+	if clue != '{' {
+		head.b.WriteString(`,"value":`)
+	}
+
+	n, err := out.Write(head.b.Bytes())
+	if err != nil {
+		return n, err
+	}
+
+	var wn int
+	if clue != '{' {
+		wn, err = js.WriteCurrentTo(out, true)
+	} else {
+		wn, err = p.writeMembers(out, js, func(k string) bool {
+			return names[k]
+		})
+	}
+	n += wn
+	if err != nil {
+		return n, err
+	}
+
+	wn, err = out.Write([]byte("}"))
+	return n + wn, err
+}
+
+func errContextNotFound(src string, w json.Where) error {
+	return &json.OrderError{
+		Where: w,
+		Err: fmt.Errorf(
+			"context (%s) wasn't found before the records it should be in, -%s values have to come before what -%s extracts",
+			src, options.OptContext, options.OptPath,
+		),
+	}
+}
+
+func errLateContext(src string, w json.Where) error {
+	return &json.OrderError{
+		Where: w,
+		Err: fmt.Errorf(
+			"context (%s) came after records it should be in, -%s values have to come before what -%s extracts",
+			src, options.OptContext, options.OptPath,
+		),
+	}
+}
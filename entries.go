@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	stdjson "encoding/json"
+	"fmt"
+	"io"
+
+	"github.com/draxil/json2nd/internal/json"
+	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
+)
+
+// writeEntries writes each member of the object under the cursor as a record
+// of its own, see -entries. Like everything else the values go straight from
+// the input to out.
+func (p processor) writeEntries(out io.Writer, js *json.JSON) error {
+	it, err := js.IterObject()
+	if err != nil {
+		return err
+	}
+
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return err
+		}
+
+		w := p.down(path.Step{Kind: path.Key, Key: string(it.Key())}).where()
+		err = p.writeEntry(out, js, it.RawKey(), w)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// writeEntry writes out the member value under the cursor as a record, key
+// is the member's key as it was in the input and w is where the value is for
+// errors.
+func (p processor) writeEntry(out io.Writer, js *json.JSON, key []byte, w json.Where) error {
+	clue := js.Peek()
+	if !json.SaneValueStart(clue) {
+		return errBadEntryValue(clue, w)
+	}
+
+	err := p.openTag(out)
+	if err != nil {
+		return err
+	}
+
+	if p.options.EntriesKey == "" {
+		_, err = fmt.Fprintf(out, `{"key":%s,"value":`, key)
+		if err == nil {
+			_, err = js.WriteCurrentTo(out, true)
+		}
+	} else {
+		err = p.writeKeyedEntry(out, js, key, w)
+	}
+	if err == io.EOF {
+		return errNonArrayEOF(json.TypeOf(clue), w)
+	}
+	if err != nil {
+		return err
+	}
+
+	// the -entries-key objects are already closed:
+	if p.options.EntriesKey == "" {
+		_, err = out.Write([]byte("}"))
+		if err != nil {
+			return err
+		}
+	}
+
+	err = p.closeTag(out)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write([]byte("\n"))
+	return err
+}
+
+// writeKeyedEntry writes out the object under the cursor with the key added
+// to it, in place of any field of the same name, see -entries-key.
+func (p processor) writeKeyedEntry(out io.Writer, js *json.JSON, key []byte, w json.Where) error {
+	clue := js.Peek()
+	if clue != '{' {
+		return errEntryNotObject(json.TypeOf(clue), w)
+	}
+
+	field, err := marshalKey(p.options.EntriesKey)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(out, "{%s:%s", field, key)
+	if err != nil {
+		return err
+	}
+
+	// the object's own field of that name would clash with ours:
+	_, err = p.writeMembers(out, js, func(k string) bool {
+		return k == p.options.EntriesKey
+	})
+	if err != nil {
+		return err
+	}
+
+	_, err = out.Write([]byte("}"))
+	return err
+}
+
+// writeMembers writes out the members of the object under the cursor, each
+// after a ',', leaving out the ones that drop says to. It moves the cursor
+// past the end of the object.
+func (p processor) writeMembers(out io.Writer, js *json.JSON, drop func(key string) bool) (int, error) {
+	it, err := js.IterObject()
+	if err != nil {
+		return 0, err
+	}
+
+	n := 0
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return n, err
+		}
+
+		if drop(string(it.Key())) {
+			err = js.Skip()
+			if err != nil {
+				return n, err
+			}
+			continue
+		}
+
+		wn, err := fmt.Fprintf(out, ",%s:", it.RawKey())
+		n += wn
+		if err != nil {
+			return n, err
+		}
+
+		wn, err = js.WriteCurrentTo(out, true)
+		n += wn
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// marshalKey gives us the JSON for a key of our own making, like
+// stdjson.Marshal but leaving <, > and & as they are. Keys from the input are
+// written as they were.
+func marshalKey(key string) ([]byte, error) {
+	b := bytes.Buffer{}
+	enc := stdjson.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	err := enc.Encode(key)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(b.Bytes(), []byte("\n")), nil
+}
+
+func errBadEntryValue(c byte, w json.Where) error {
+	return &json.SyntaxError{
+		Where: w,
+		Err:   fmt.Errorf("the value of (%s) doesn't look like JSON, starts with: %c", w.Path, c),
+	}
+}
+
+func errEntryNotObject(t string, w json.Where) error {
+	return &json.TypeMismatchError{
+		Where:    w,
+		Expected: "object",
+		Found:    t,
+		Err:      fmt.Errorf("-%s needs each value to be an object but (%s) was %s", options.OptEntriesKey, w.Path, t),
+	}
+}
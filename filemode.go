@@ -1,14 +1,17 @@
 package main
 
 import (
+	"errors"
 	"fmt"
-	"io"
 	"os"
-
-	"github.com/draxil/json2nd/internal/options"
 )
 
-func filemode(files []string, out io.Writer, opts options.Set) error {
+// filemode runs the processor over each of the files in turn.
+func filemode(files []string, p processor) error {
+
+	// a file that -salvage has got what it could from doesn't stop us,
+	// but we do report it:
+	var partial error
 
 	for _, name := range files {
 		f, err := os.Open(name)
@@ -16,19 +19,42 @@ func filemode(files []string, out io.Writer, opts options.Set) error {
 			return fileOpenErr(name, err)
 		}
 
-		p := processor{f, out, opts, true}
+		p.in = f
+		p.name = name
 		err = p.run()
+		f.Close()
+		if errors.As(err, &partialErr{}) {
+			if partial == nil {
+				partial = fileProcessErr(name, err)
+			}
+			continue
+		}
 		if err != nil {
 			return fileProcessErr(name, err)
 		}
 	}
-	return nil
+	return partial
 }
 
 func fileOpenErr(file string, e error) error {
-	return fmt.Errorf("could not open %s: %w", file, e)
+	return fileErr{file, "open", e}
 }
 
 func fileProcessErr(file string, e error) error {
-	return fmt.Errorf("could not process %s: %w", file, e)
+	return fileErr{file, "process", e}
+}
+
+// fileErr is something that went wrong with one of the files we were given.
+type fileErr struct {
+	name  string
+	doing string
+	err   error
+}
+
+func (e fileErr) Error() string {
+	return fmt.Sprintf("could not %s %s: %v", e.doing, e.name, e.err)
+}
+
+func (e fileErr) Unwrap() error {
+	return e.err
 }
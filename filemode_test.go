@@ -54,19 +54,72 @@ func TestFileMode(t *testing.T) {
 				assert.NoError(t, e)
 			},
 			opts: options.Set{
-				Path: "x",
+				Paths: []string{"x"},
 			},
 			exp: `1` + "\n" + `2` + "\n" + `4` + "\n",
 		},
+		{
+			name:  "with a path that's missing from one file",
+			files: []string{"./testdata/simpleobj.json", "./testdata/other.json", "./testdata/simpleobj.json"},
+			checkErr: func(t *testing.T, e error) {
+				if assert.Error(t, e) {
+					assert.Contains(t, e.Error(), "could not process ./testdata/other.json")
+				}
+			},
+			opts: options.Set{
+				Paths: []string{"x"},
+			},
+			exp: `1` + "\n" + `2` + "\n" + `4` + "\n",
+		},
+		{
+			name:  "skipping a file with the path missing",
+			files: []string{"./testdata/other.json", "./testdata/simpleobj.json"},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+			opts: options.Set{
+				Paths:   []string{"x"},
+				Missing: options.MissingSkip,
+			},
+			exp: `1` + "\n" + `2` + "\n" + `4` + "\n",
+		},
+		{
+			name:  "salvaging a file that's cut off",
+			files: []string{"./testdata/cut.json", "./testdata/1.json"},
+			checkErr: func(t *testing.T, e error) {
+				if assert.Error(t, e) {
+					assert.True(t, errors.As(e, &partialErr{}), "partial")
+					assert.Contains(t, e.Error(), "could not process ./testdata/cut.json")
+				}
+			},
+			opts: options.Set{
+				Salvage: true,
+			},
+			exp: `{"a":1}` + "\n" + `{"b":2}` + "\n" + `{"one":1}` + "\n",
+		},
 		// TODO BAD FILE
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
 			out := bytes.NewBuffer(nil)
-			err := filemode(tc.files, out, tc.opts)
+			err := filemode(tc.files, processor{out: out, options: tc.opts, buffered: true})
 			assert.Equal(t, tc.exp, out.String(), "output")
 			tc.checkErr(t, err)
 		})
 	}
 }
+
+func TestFileModeSkipWarning(t *testing.T) {
+	out := bytes.NewBuffer(nil)
+	warn := bytes.NewBuffer(nil)
+	opts := options.Set{
+		Paths:   []string{"x"},
+		Missing: options.MissingSkip,
+	}
+
+	err := filemode([]string{"./testdata/other.json"}, processor{out: out, options: opts, warn: warn})
+	assert.NoError(t, err)
+	assert.Equal(t, "", out.String(), "output")
+	assert.Equal(t, "skipped ./testdata/other.json: path node did not exist: x\n", warn.String(), "warning")
+}
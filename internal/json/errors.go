@@ -0,0 +1,189 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Where is where an error happened, it's shared by the error types below so
+// you can find it with errors.As whatever kind of error it is.
+type Where struct {
+	// Offset in bytes into the input, filled in by Locate
+	Offset int64
+	// Index of the array value we were on, -1 if we weren't on one
+	Index int
+	// Path we'd followed to get there, blank at the top level
+	Path string
+}
+
+func (w *Where) where() *Where {
+	return w
+}
+
+// located is any of our errors which has a Where.
+type located interface {
+	error
+	where() *Where
+}
+
+// WhereOf finds the Where of the first of our errors in err's chain.
+func WhereOf(err error) (Where, bool) {
+	var typed located
+	if !errors.As(err, &typed) {
+		return Where{}, false
+	}
+	return *typed.where(), true
+}
+
+// SyntaxError is JSON that we couldn't make sense of.
+type SyntaxError struct {
+	Where
+	Err error
+}
+
+func (e *SyntaxError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// UnexpectedEOFError is input that ended part way through the JSON. It also
+// counts as io.ErrUnexpectedEOF for errors.Is.
+type UnexpectedEOFError struct {
+	Where
+	Err error
+}
+
+func (e *UnexpectedEOFError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *UnexpectedEOFError) Unwrap() error {
+	return e.Err
+}
+
+func (e *UnexpectedEOFError) Is(target error) bool {
+	return target == io.ErrUnexpectedEOF
+}
+
+// PathNotFoundError is a path which lead somewhere that doesn't exist, Node
+// is the part of the path that we couldn't find.
+type PathNotFoundError struct {
+	Where
+	Node string
+}
+
+func (e *PathNotFoundError) Error() string {
+	return fmt.Sprintf("path node did not exist: %s", e.Node)
+}
+
+// TypeMismatchError is a value which isn't the type we needed, e.g a path
+// going through a number. Found is blank if the value didn't look like JSON
+// at all.
+type TypeMismatchError struct {
+	Where
+	Expected string
+	Found    string
+	Err      error
+}
+
+func (e *TypeMismatchError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TypeMismatchError) Unwrap() error {
+	return e.Err
+}
+
+// OrderError is a value that turned up after it was needed, e.g a -context
+// found after the records it was for. The JSON is fine, it's just not in an
+// order we can use in one pass.
+type OrderError struct {
+	Where
+	Err error
+}
+
+func (e *OrderError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *OrderError) Unwrap() error {
+	return e.Err
+}
+
+// Classify gives the lower level errors that come out of JSON one of the
+// types above, at w. Errors which already have a type, or which aren't about
+// the JSON, are returned as they are.
+func Classify(err error, w Where) error {
+	if err == nil {
+		return nil
+	}
+
+	var typed located
+	if errors.As(err, &typed) {
+		return err
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return &UnexpectedEOFError{w, err}
+	}
+
+	var invalid ErrInvalidJSON
+	if errors.As(err, &invalid) {
+		if invalid.Problem == problemEOF {
+			return &UnexpectedEOFError{w, err}
+		}
+		return &SyntaxError{w, err}
+	}
+
+	var notObject ErrScanNotObject
+	if errors.As(err, &notObject) {
+		return &TypeMismatchError{w, "object", TypeOf(notObject.On), err}
+	}
+
+	var notArray ErrScanNotArray
+	if errors.As(err, &notArray) {
+		return &TypeMismatchError{w, "array", TypeOf(notArray.On), err}
+	}
+
+	var (
+		number     ErrBadNumber
+		value      ErrBadValue
+		jsonValue  ErrBadJSONValue
+		unexpected ErrUnexpected
+	)
+	if errors.As(err, &number) || errors.As(err, &value) || errors.As(err, &jsonValue) || errors.As(err, &unexpected) {
+		return &SyntaxError{w, err}
+	}
+
+	return err
+}
+
+// TypeOf guesses the type of a JSON value from its first byte, blank if it
+// doesn't look like the start of a value.
+func TypeOf(clue byte) string {
+
+	switch clue {
+	case '{':
+		return "object"
+	case '"':
+		return "string"
+	case '[':
+		return "array"
+	case 't':
+		return "boolean"
+	case 'f':
+		return "boolean"
+	case 'n':
+		return "null"
+	}
+
+	if (clue >= '0' && clue <= '9') || clue == '-' {
+		return "number"
+	}
+
+	return ""
+}
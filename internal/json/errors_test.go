@@ -0,0 +1,146 @@
+package json
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassify(t *testing.T) {
+	w := Where{Index: 2, Path: "a.b"}
+
+	cases := []struct {
+		name string
+		in   error
+		exp  error
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "EOF",
+			in:   io.EOF,
+			exp:  &UnexpectedEOFError{w, io.EOF},
+		},
+		{
+			name: "strict EOF",
+			in:   ErrInvalidJSON{5, problemEOF},
+			exp:  &UnexpectedEOFError{w, ErrInvalidJSON{5, problemEOF}},
+		},
+		{
+			name: "invalid",
+			in:   ErrInvalidJSON{5, "expected ':' but found '1'"},
+			exp:  &SyntaxError{w, ErrInvalidJSON{5, "expected ':' but found '1'"}},
+		},
+		{
+			name: "bad number",
+			in:   ErrBadNumber{1, "it has a leading zero"},
+			exp:  &SyntaxError{w, ErrBadNumber{1, "it has a leading zero"}},
+		},
+		{
+			name: "bad value",
+			in:   ErrBadValue{"truex"},
+			exp:  &SyntaxError{w, ErrBadValue{"truex"}},
+		},
+		{
+			name: "unexpected",
+			in:   ErrUnexpected{',', '2'},
+			exp:  &SyntaxError{w, ErrUnexpected{',', '2'}},
+		},
+		{
+			name: "not an object",
+			in:   ErrScanNotObject{'['},
+			exp:  &TypeMismatchError{w, "object", "array", ErrScanNotObject{'['}},
+		},
+		{
+			name: "not an array",
+			in:   ErrScanNotArray{'1'},
+			exp:  &TypeMismatchError{w, "array", "number", ErrScanNotArray{'1'}},
+		},
+		{
+			name: "already typed",
+			in:   &PathNotFoundError{Where{Index: -1}, "x"},
+			exp:  &PathNotFoundError{Where{Index: -1}, "x"},
+		},
+		{
+			name: "nothing to do with JSON",
+			in:   io.ErrClosedPipe,
+			exp:  io.ErrClosedPipe,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.exp, Classify(tc.in, w))
+		})
+	}
+}
+
+func TestTypedErrors(t *testing.T) {
+	j := New(strings.NewReader(`{"a":[1, 2,`))
+	_, err := j.ScanForKeyValue("a")
+	assert.NoError(t, err)
+
+	_, err = j.ScanForIndex(3)
+	err = j.Locate(Classify(err, Where{Index: 2, Path: "a"}))
+
+	assert.True(t, errors.Is(err, io.ErrUnexpectedEOF), "is an unexpected EOF")
+	assert.True(t, errors.Is(err, io.EOF), "still has the EOF")
+
+	var eof *UnexpectedEOFError
+	if assert.True(t, errors.As(err, &eof)) {
+		assert.Equal(t, Where{Offset: 11, Index: 2, Path: "a"}, eof.Where)
+	}
+
+	var syntax *SyntaxError
+	assert.False(t, errors.As(err, &syntax))
+
+	var mismatch *TypeMismatchError
+	err = fmt.Errorf("wrapped: %w", Classify(ErrScanNotObject{'"'}, Where{Index: -1}))
+	if assert.True(t, errors.As(err, &mismatch)) {
+		assert.Equal(t, "object", mismatch.Expected)
+		assert.Equal(t, "string", mismatch.Found)
+		assert.Equal(t, ErrScanNotObject{'"'}, errors.Unwrap(mismatch))
+	}
+
+	order := &OrderError{Where{Index: -1, Path: "meta"}, io.ErrNoProgress}
+	assert.Equal(t, order, Classify(order, Where{}), "already has a type")
+	w, ok := WhereOf(fmt.Errorf("wrapped: %w", order))
+	assert.True(t, ok)
+	assert.Equal(t, "meta", w.Path)
+}
+
+func TestErrInternalUnwraps(t *testing.T) {
+	assert.True(t, errors.Is(ErrInternal{io.ErrShortWrite}, io.ErrShortWrite))
+}
+
+func TestTypeOf(t *testing.T) {
+
+	cases := []struct {
+		in  byte
+		exp string
+	}{
+		{'{', "object"},
+		{'"', "string"},
+		{'5', "number"},
+		{'1', "number"},
+		{'0', "number"},
+		{'9', "number"},
+		{'-', "number"},
+		{'x', ""},
+		{'\'', ""},
+		{'[', "array"},
+	}
+
+	for _, tc := range cases {
+		name := fmt.Sprintf("%c -> %s", tc.in, tc.exp)
+		t.Run(name, func(t *testing.T) {
+			get := TypeOf(tc.in)
+			assert.Equal(t, tc.exp, get)
+		})
+	}
+}
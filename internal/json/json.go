@@ -1,12 +1,14 @@
 package json
 
-// TODO: MASTER OFFSET FOR ERRORS?
 // TODO: unicode considerations?
 // TODO: BETTER ERROR HANDLING
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"strings"
 )
 
 type JSON struct {
@@ -15,11 +17,38 @@ type JSON struct {
 	idx       int
 	bytes     int
 	chunkSize int
+	// validator checks what we read, when we're being strict
+	validator *validator
+	// invalid is a problem the validator found, which we report once
+	// we've used up the data before it
+	invalid error
+	// read is how much is in buf, which can be more than bytes when
+	// what follows a problem has been hidden
+	read int
+	// base is the offset of the start of buf in the whole input, lines is
+	// how many lines came before it, and lineStart is the offset the last
+	// of those started at
+	base      int64
+	lines     int
+	lineStart int64
+	// recent is what came before buf, for showing errors in context
+	recent ring
+	// capture is where we're keeping a copy of what we go through, from
+	// captureFrom in buf, see Capture
+	capture     *bytes.Buffer
+	captureFrom int
 }
 
 func New(r io.Reader) *JSON {
 	const defaultChunkSize = 4096
-	return &JSON{r, nil, -1, 0, defaultChunkSize}
+	return &JSON{r: r, idx: -1, chunkSize: defaultChunkSize}
+}
+
+// Strict makes sure that everything read is valid JSON (RFC 8259), or at
+// least a stream of valid JSON values. When it's not we stop with an
+// ErrInvalidJSON as we get to the problem.
+func (j *JSON) Strict() {
+	j.validator = &validator{}
 }
 
 func (j *JSON) data() (bool, error) {
@@ -32,20 +61,154 @@ func (j *JSON) data() (bool, error) {
 		return true, nil
 	}
 
+	if j.invalid != nil {
+		return false, j.invalid
+	}
+
+	j.moveBase()
 	j.idx = 0
 
 	var err error
 	j.bytes, err = j.r.Read(j.buf)
+	j.read = j.bytes
 	if err == io.EOF {
+		if j.validator != nil {
+			return false, j.validator.end()
+		}
 		return false, nil
 	}
 	if err != nil {
 		return false, err
 	}
 
+	if j.validator != nil {
+		// only let the good part of the chunk be seen:
+		j.bytes, j.invalid = j.validator.check(j.buf[:j.bytes])
+		if j.bytes == 0 && j.invalid != nil {
+			return false, j.invalid
+		}
+	}
+
 	return true, nil
 }
 
+// moveBase moves our idea of where buf starts past the chunk in it.
+func (j *JSON) moveBase() {
+	chunk := j.buf[:j.bytes]
+
+	n := bytes.Count(chunk, []byte{'\n'})
+	if n > 0 {
+		j.lines += n
+		j.lineStart = j.base + int64(bytes.LastIndexByte(chunk, '\n')) + 1
+	}
+	j.base += int64(j.bytes)
+	j.recent.write(chunk)
+
+	if j.capture != nil {
+		if j.captureFrom < len(chunk) {
+			j.capture.Write(chunk[j.captureFrom:])
+		}
+		j.captureFrom = 0
+	}
+}
+
+// Position is somewhere in the JSON.
+type Position struct {
+	// Offset in bytes from the start
+	Offset int64
+	// Line and Column start at 1, the column is in bytes
+	Line   int
+	Column int
+}
+
+func (p Position) String() string {
+	return fmt.Sprintf("line %d, column %d (byte offset %d)", p.Line, p.Column, p.Offset)
+}
+
+// Position of the cursor.
+func (j *JSON) Position() Position {
+	return j.positionOf(j.idx)
+}
+
+func (j *JSON) positionOf(idx int) Position {
+	if idx < 0 {
+		idx = 0
+	}
+	if idx > j.bytes {
+		idx = j.bytes
+	}
+
+	chunk := j.buf[:idx]
+	lines := j.lines
+	lineStart := j.lineStart
+
+	n := bytes.Count(chunk, []byte{'\n'})
+	if n > 0 {
+		lines += n
+		lineStart = j.base + int64(bytes.LastIndexByte(chunk, '\n')) + 1
+	}
+
+	offset := j.base + int64(idx)
+	return Position{
+		Offset: offset,
+		Line:   lines + 1,
+		Column: int(offset-lineStart) + 1,
+	}
+}
+
+// ErrAt is an error and where in the JSON it happened.
+type ErrAt struct {
+	Position
+	Err error
+	// Snippet of the input around the problem, not part of the error
+	// message as it's several lines long
+	Snippet Snippet
+}
+
+func (e ErrAt) Error() string {
+	return fmt.Sprintf("%v, at %s", e.Err, e.Position)
+}
+
+func (e ErrAt) Unwrap() error {
+	return e.Err
+}
+
+// Locate gives us err with the position we'd got to when it happened. That's
+// the cursor, unless err knows better. It also fills in the Offset of err's
+// Where, if it has one.
+func (j *JSON) Locate(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var at ErrAt
+	if errors.As(err, &at) {
+		return err
+	}
+
+	idx := j.idx
+	var invalid ErrInvalidJSON
+	if errors.As(err, &invalid) {
+		idx = int(invalid.Offset - j.base)
+	}
+
+	if idx < 0 {
+		idx = 0
+	}
+	if idx > j.bytes {
+		idx = j.bytes
+	}
+
+	at = ErrAt{j.positionOf(idx), err, j.snippetAt(idx)}
+
+	var typed located
+	if errors.As(err, &typed) {
+		typed.where().Offset = at.Offset
+	}
+
+	return at
+}
+
 func (j *JSON) Peek() byte {
 	if j.buf == nil {
 		return 0
@@ -93,6 +256,19 @@ func closerFor(b byte) byte {
 }
 func (j *JSON) ScanForKeyValue(k string) (bool, error) {
 	found, err := j.ScanForKey(k)
+	return j.onToValue(found, err)
+}
+
+// ScanForNextKeyValue carries on through the object the cursor is inside of
+// (it must be between members) looking for another key k, see
+// ScanForKeyValue.
+func (j *JSON) ScanForNextKeyValue(k string) (bool, error) {
+	found, err := j.scanOnForKey(k)
+	return j.onToValue(found, err)
+}
+
+// onToValue moves from a key we've found on to its value.
+func (j *JSON) onToValue(found bool, err error) (bool, error) {
 	if err != nil {
 		return false, err
 	}
@@ -106,7 +282,7 @@ func (j *JSON) ScanForKeyValue(k string) (bool, error) {
 		return false, err
 	}
 	if c != ':' {
-		return false, fmt.Errorf("expected ':' found %c", c)
+		return false, ErrUnexpected{':', c}
 	}
 
 	j.MoveOff()
@@ -118,6 +294,9 @@ func (j *JSON) ScanForKeyValue(k string) (bool, error) {
 
 	return true, nil
 }
+
+// ScanForKey moves the cursor through the object under it until it finds key
+// k, if there's no such key the cursor is left after the object.
 func (j *JSON) ScanForKey(k string) (bool, error) {
 	start, err := j.Next()
 	if err != nil {
@@ -131,7 +310,11 @@ func (j *JSON) ScanForKey(k string) (bool, error) {
 	// kick cursor into the object
 	j.MoveOff()
 
-	scanner := NewScanState(start)
+	return j.scanOnForKey(k)
+}
+
+func (j *JSON) scanOnForKey(k string) (bool, error) {
+	scanner := NewScanState('{')
 	scanner.seekFor(k)
 
 	for {
@@ -148,6 +331,8 @@ func (j *JSON) ScanForKey(k string) (bool, error) {
 		}
 
 		if !scanner.open {
+			// move past the end of the object:
+			j.MoveOff()
 			return false, nil
 		}
 
@@ -174,6 +359,279 @@ func (ErrScanNotObject) Error() string {
 	return "can't scan for key, not on an object"
 }
 
+// ScanForIndex moves the cursor on to the value at index i of the array under
+// the cursor, skipping over the values before it.
+func (j *JSON) ScanForIndex(i int) (bool, error) {
+	it, err := j.IterArray()
+	if err != nil {
+		return false, err
+	}
+
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return false, err
+		}
+
+		if it.Index() == i {
+			return true, nil
+		}
+
+		err = j.Skip()
+		if err != nil {
+			return false, err
+		}
+	}
+}
+
+type ErrScanNotArray struct {
+	On byte
+}
+
+func (ErrScanNotArray) Error() string {
+	return "can't scan for index, not on an array"
+}
+
+// ArrayIter walks the values of an array, see IterArray.
+type ArrayIter struct {
+	j     *JSON
+	index int
+	done  bool
+}
+
+// IterArray starts iterating over the array under the cursor. Each call to
+// Next leaves the cursor on the next value, which must be consumed (written or
+// skipped) before calling Next again.
+func (j *JSON) IterArray() (*ArrayIter, error) {
+	start, err := j.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	if start != '[' {
+		return nil, ErrScanNotArray{start}
+	}
+
+	j.MoveOff()
+
+	return &ArrayIter{j: j, index: -1}, nil
+}
+
+// Next moves on to the next value in the array, false means we've reached the
+// end of the array and the cursor is now past it.
+func (a *ArrayIter) Next() (bool, error) {
+	if a.done {
+		return false, nil
+	}
+
+	c, err := a.j.Next()
+	if err != nil {
+		return false, err
+	}
+
+	if a.index >= 0 && c == ',' {
+		a.j.MoveOff()
+		c, err = a.j.Next()
+		if err != nil {
+			return false, err
+		}
+	} else if a.index >= 0 && c != ']' {
+		return false, ErrUnexpected{',', c}
+	}
+
+	if c == ']' {
+		a.j.MoveOff()
+		a.done = true
+		return false, nil
+	}
+
+	a.index++
+	return true, nil
+}
+
+// Index of the value the cursor is on.
+func (a *ArrayIter) Index() int {
+	return a.index
+}
+
+// ObjectIter walks the members of an object, see IterObject.
+type ObjectIter struct {
+	j       *JSON
+	key     []byte
+	rawKey  []byte
+	started bool
+	done    bool
+}
+
+// IterObject starts iterating over the object under the cursor. Each call to
+// Next reads a key and leaves the cursor on its value, which must be consumed
+// (written or skipped) before calling Next again.
+func (j *JSON) IterObject() (*ObjectIter, error) {
+	start, err := j.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	if start != '{' {
+		return nil, ErrScanNotObject{start}
+	}
+
+	j.MoveOff()
+
+	return &ObjectIter{j: j}, nil
+}
+
+// Next moves on to the next member of the object, false means we've reached
+// the end of the object and the cursor is now past it.
+func (o *ObjectIter) Next() (bool, error) {
+	if o.done {
+		return false, nil
+	}
+
+	c, err := o.j.Next()
+	if err != nil {
+		return false, err
+	}
+
+	if o.started && c == ',' {
+		o.j.MoveOff()
+		c, err = o.j.Next()
+		if err != nil {
+			return false, err
+		}
+	} else if o.started && c != '}' {
+		return false, ErrUnexpected{',', c}
+	}
+
+	if c == '}' {
+		o.j.MoveOff()
+		o.done = true
+		return false, nil
+	}
+
+	if c != '"' {
+		return false, ErrUnexpected{'"', c}
+	}
+
+	o.started = true
+	o.key, o.rawKey, err = o.j.readString(o.key[:0], o.rawKey[:0])
+	if err != nil {
+		return false, err
+	}
+
+	c, err = o.j.Next()
+	if err != nil {
+		return false, err
+	}
+	if c != ':' {
+		return false, ErrUnexpected{':', c}
+	}
+
+	o.j.MoveOff()
+	_, err = o.j.Next()
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Key of the member the cursor is on, with any escapes decoded. Only valid
+// until the next call to Next.
+func (o *ObjectIter) Key() []byte {
+	return o.key
+}
+
+// RawKey is the key of the member the cursor is on as it was in the input,
+// quotes and all. Only valid until the next call to Next.
+func (o *ObjectIter) RawKey() []byte {
+	return o.rawKey
+}
+
+// readString appends the decoded contents of the string under the cursor to
+// dst, and the string as it is in the input to raw, leaving the cursor after
+// the closing quote.
+func (j *JSON) readString(dst, raw []byte) ([]byte, []byte, error) {
+	raw = append(raw, '"')
+	j.MoveOff()
+	escaped := false
+	var u unescaper
+
+	for {
+		more, err := j.data()
+		if err != nil {
+			return dst, raw, err
+		}
+		if !more {
+			return dst, raw, io.EOF
+		}
+
+		for ; j.idx < j.bytes; j.idx++ {
+			c := j.buf[j.idx]
+			raw = append(raw, c)
+			if escaped {
+				escaped = false
+			} else if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				out, n := u.end()
+				dst = append(dst, out[:n]...)
+				j.MoveOff()
+				return dst, raw, nil
+			}
+
+			out, n := u.next(c)
+			dst = append(dst, out[:n]...)
+		}
+	}
+}
+
+// Skip moves the cursor past the value under it.
+func (j *JSON) Skip() error {
+	_, err := j.Next()
+	if err != nil {
+		return err
+	}
+
+	_, err = j.WriteCurrentTo(io.Discard, true)
+	return err
+}
+
+// SkipRest moves the cursor past the end of the array or object it is
+// currently inside of, in is the character that opened it. The cursor must be
+// between values, not part way through one.
+func (j *JSON) SkipRest(in byte) error {
+	scanner := NewScanState(in)
+
+	for {
+		var err error
+
+		j.idx, err = scanner.scan(j.buf, j.idx, j.bytes)
+		if err != nil {
+			return err
+		}
+
+		if !scanner.open {
+			j.MoveOff()
+			return nil
+		}
+
+		// the scanner stops on newlines outside of strings:
+		if j.idx < j.bytes && j.buf[j.idx] == '\n' {
+			j.MoveOff()
+			continue
+		}
+
+		more, err := j.data()
+		if err != nil {
+			return err
+		}
+		if !more {
+			return io.EOF
+		}
+	}
+}
+
 func (j *JSON) WriteCurrentTo(w io.Writer, includeDeliminators bool) (int, error) {
 	// TODO: note sure all delims schenarios are covered with no delim types
 
@@ -254,9 +712,10 @@ func (j *JSON) WriteCurrentTo(w io.Writer, includeDeliminators bool) (int, error
 	return alreadyWritten, nil
 }
 
-// NOTE: Would allow some bad values such 1.....4 or 1e+-2134 but remember
-// JSON validation isn't really our jam.
+// writeCurrentNumber writes out the number under the cursor, checking it
+// follows the JSON number grammar as it goes.
 func (j *JSON) writeCurrentNumber(w io.Writer) (int, error) {
+	var num number
 	end := false
 	written := 0
 	start := 0
@@ -265,13 +724,12 @@ func (j *JSON) writeCurrentNumber(w io.Writer) (int, error) {
 		start = j.idx
 
 		for ; j.idx < j.bytes; j.idx++ {
-			c := j.buf[j.idx]
-			if !((c >= '0' && c <= '9') ||
-				c == '.' ||
-				c == 'e' ||
-				c == 'E' ||
-				c == '-' ||
-				c == '+') {
+			more, err := num.next(j.buf[j.idx])
+			if err != nil {
+				// (we've not written out this chunk of it yet)
+				return written, ErrBadNumber{written + j.idx - start, err.Error()}
+			}
+			if !more {
 				end = true
 				break
 			}
@@ -295,11 +753,21 @@ func (j *JSON) writeCurrentNumber(w io.Writer) (int, error) {
 
 	}
 
+	err := num.end()
+	if err != nil && !end {
+		// the input ran out part way through it
+		return written, io.EOF
+	}
+	if err != nil {
+		return written, ErrBadNumber{written, err.Error()}
+	}
+
 	return written, nil
 }
 
 func (j *JSON) writeKeywordValue(w io.Writer) (int, error) {
 	end := false
+	eof := false
 	stored := 0
 	written := 0
 	var keyword [5]byte
@@ -323,6 +791,7 @@ func (j *JSON) writeKeywordValue(w io.Writer) (int, error) {
 			}
 			if !more {
 				end = true
+				eof = true
 			}
 		}
 
@@ -332,7 +801,10 @@ func (j *JSON) writeKeywordValue(w io.Writer) (int, error) {
 			if !(keystring == "true" ||
 				keystring == "false" ||
 				keystring == "null") {
-				return 0, ErrBadValue{string(keyword[:])}
+				if eof && keywordStart(keystring) {
+					return 0, io.EOF
+				}
+				return 0, ErrBadValue{keystring}
 			}
 
 			return w.Write(keyslice)
@@ -342,12 +814,16 @@ func (j *JSON) writeKeywordValue(w io.Writer) (int, error) {
 	return written, nil
 }
 
+// keywordStart is true if s is the start of one of the JSON keywords.
+func keywordStart(s string) bool {
+	return strings.HasPrefix("true", s) || strings.HasPrefix("false", s) || strings.HasPrefix("null", s)
+}
+
 func errNoObject() error {
 	// TODO: internal error system?
 	return ErrInternal{fmt.Errorf("no current object")}
 }
 
-// TODO: unwrappable
 type ErrInternal struct {
 	inner error
 }
@@ -356,6 +832,21 @@ func (e ErrInternal) Error() string {
 	return fmt.Sprintf("internal error: %v", e.inner)
 }
 
+func (e ErrInternal) Unwrap() error {
+	return e.inner
+}
+
+// ErrBadNumber is a number that doesn't follow the JSON number grammar.
+type ErrBadNumber struct {
+	// At is how far into the number the problem is
+	At      int
+	Problem string
+}
+
+func (e ErrBadNumber) Error() string {
+	return fmt.Sprintf("bad number, %s (at character %d of the number)", e.Problem, e.At+1)
+}
+
 type ErrBadValue struct {
 	Value string
 }
@@ -364,6 +855,17 @@ func (e ErrBadValue) Error() string {
 	return fmt.Sprintf("bad value: %s", e.Value)
 }
 
+// ErrUnexpected is a byte we didn't expect between the values of an array or
+// object.
+type ErrUnexpected struct {
+	Expected byte
+	Found    byte
+}
+
+func (e ErrUnexpected) Error() string {
+	return fmt.Sprintf("expected '%c' found %c", e.Expected, e.Found)
+}
+
 func isSpace(c byte) bool {
 	return c <= ' ' && (c == ' ' || c == '\t' || c == '\r' || c == '\n')
 }
@@ -375,7 +877,7 @@ func SaneValueStart(c byte) bool {
 	}
 	return (c == 'n' || // for null
 		c == 't' || // for true
-		(c >= '1' && c <= '9') ||
+		(c >= '0' && c <= '9') ||
 		c == '-' ||
 		c == 'f') // for false
 
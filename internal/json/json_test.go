@@ -1,6 +1,7 @@
 package json
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -272,11 +273,86 @@ func TestCurrentWriteTo(t *testing.T) {
 			expClue: '-',
 		},
 		{
-			name:    "show effect of delims on an awkward number",
+			name:    "awkward number stops where the number grammar does",
 			in:      sread(" -12.01-210, "),
 			delims:  true,
-			exp:     "-12.01-210",
+			exp:     "-12.01",
+			expClue: '-',
+		},
+		{
+			name:    "target is zero",
+			in:      sread("0,"),
+			delims:  true,
+			exp:     "0",
+			expClue: '0',
+		},
+		{
+			name:    "target is every part of a number",
+			in:      sread("-0.5e+10]"),
+			delims:  true,
+			exp:     "-0.5e+10",
+			expClue: '-',
+		},
+		{
+			name:    "number with a leading zero",
+			in:      sread("01"),
+			delims:  true,
+			exp:     "",
+			expClue: '0',
+			checkErr: func(t *testing.T, e error) {
+				assert.Equal(t, ErrBadNumber{1, "it has a leading zero"}, e)
+			},
+		},
+		{
+			name:    "number with no fraction digits",
+			in:      sread("1.e-23"),
+			delims:  true,
+			exp:     "",
+			expClue: '1',
+			checkErr: func(t *testing.T, e error) {
+				assert.Equal(t, ErrBadNumber{2, "expected a digit after the decimal point but found 'e'"}, e)
+			},
+		},
+		{
+			name:    "number with a problem after the first chunk",
+			in:      sread("1234e+-5"),
+			delims:  true,
+			exp:     "1234e+",
+			expClue: '1',
+			checkErr: func(t *testing.T, e error) {
+				assert.Equal(t, ErrBadNumber{6, "expected an exponent but found '-'"}, e)
+				assert.EqualError(t, e, "bad number, expected an exponent but found '-' (at character 7 of the number)")
+			},
+		},
+		{
+			name:    "input ends part way through a number",
+			in:      sread("-"),
+			delims:  true,
+			exp:     "-",
 			expClue: '-',
+			checkErr: func(t *testing.T, e error) {
+				assert.Equal(t, io.EOF, e)
+			},
+		},
+		{
+			name:    "input ends part way through a keyword",
+			in:      sread("fal"),
+			delims:  true,
+			exp:     "",
+			expClue: 'f',
+			checkErr: func(t *testing.T, e error) {
+				assert.Equal(t, io.EOF, e)
+			},
+		},
+		{
+			name:    "bad keyword",
+			in:      sread("tr,"),
+			delims:  true,
+			exp:     "",
+			expClue: 't',
+			checkErr: func(t *testing.T, e error) {
+				assert.Equal(t, ErrBadValue{"tr"}, e)
+			},
 		},
 		{
 			name:    "simple newline infested array (+delims)",
@@ -423,6 +499,195 @@ func TestScanForKeyValueSimple(t *testing.T) {
 	assert.Equal(t, `"v"`, b.String(), "value")
 }
 
+func TestScanForIndex(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		reader   io.Reader
+		index    int
+		expErr   error
+		expFound bool
+		expValue string
+	}{
+		{
+			name:   "not array error",
+			reader: sread(" {"),
+			expErr: ErrScanNotArray{'{'},
+		},
+		{
+			name:   "empty array",
+			reader: sread("[]"),
+		},
+		{
+			name:   "index past the end",
+			reader: sread("[1, 2]"),
+			index:  2,
+		},
+		{
+			name:     "first value",
+			reader:   sread("[1, 2]"),
+			expFound: true,
+			expValue: "1",
+		},
+		{
+			name:     "skip nested values",
+			reader:   sread(`[[1,[2]], {"x":"]"}, "\"]", 4]`),
+			index:    3,
+			expFound: true,
+			expValue: "4",
+		},
+		{
+			name:     "newline infested",
+			reader:   sread("[\n1\n,\n{\n}\n,\n\"x\"\n]"),
+			index:    2,
+			expFound: true,
+			expValue: `"x"`,
+		},
+		{
+			name:   "missing comma",
+			reader: sread("[1 2]"),
+			index:  1,
+			expErr: ErrUnexpected{',', '2'},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			j := New(tc.reader)
+			j.chunkSize = 3
+			found, err := j.ScanForIndex(tc.index)
+			assert.Equal(t, tc.expFound, found, "found")
+			assert.Equal(t, tc.expErr, err, "err")
+
+			if found {
+				b := strings.Builder{}
+				_, err = j.WriteCurrentTo(&b, true)
+				assert.NoError(t, err)
+				assert.Equal(t, tc.expValue, b.String(), "value")
+			}
+		})
+	}
+}
+
+func TestSkipRest(t *testing.T) {
+	j := New(sread(`{"x":1,` + "\n" + `"y":{"z":"}"}} "after"`))
+	j.chunkSize = 4
+
+	found, err := j.ScanForKeyValue("x")
+	assert.NoError(t, err)
+	assert.True(t, found, "found")
+
+	err = j.Skip()
+	assert.NoError(t, err, "skip")
+
+	err = j.SkipRest('{')
+	assert.NoError(t, err, "skip rest")
+
+	b := strings.Builder{}
+	_, err = j.Next()
+	assert.NoError(t, err)
+	_, err = j.WriteCurrentTo(&b, true)
+	assert.NoError(t, err)
+	assert.Equal(t, `"after"`, b.String(), "next value")
+}
+
+func TestSkipRestEOF(t *testing.T) {
+	j := New(sread(`[1, 2`))
+	_, err := j.IterArray()
+	assert.NoError(t, err)
+
+	err = j.SkipRest('[')
+	assert.Equal(t, io.EOF, err)
+}
+
+func TestIterObject(t *testing.T) {
+	j := New(sread(`{"a":1, "b\"" : {"c":[2]},` + "\n" + `"":"x"} 5`))
+	j.chunkSize = 3
+
+	it, err := j.IterObject()
+	assert.NoError(t, err)
+
+	var keys []string
+	var rawKeys []string
+	var values []string
+	for {
+		more, err := it.Next()
+		assert.NoError(t, err)
+		if !more {
+			break
+		}
+		keys = append(keys, string(it.Key()))
+		rawKeys = append(rawKeys, string(it.RawKey()))
+
+		b := strings.Builder{}
+		_, err = j.WriteCurrentTo(&b, true)
+		assert.NoError(t, err)
+		values = append(values, b.String())
+	}
+
+	assert.Equal(t, []string{"a", `b"`, ""}, keys, "keys")
+	assert.Equal(t, []string{`"a"`, `"b\""`, `""`}, rawKeys, "raw keys")
+	assert.Equal(t, []string{"1", `{"c":[2]}`, `"x"`}, values, "values")
+
+	c, err := j.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, byte('5'), c, "moved past the object")
+}
+
+func TestIterObjectErrors(t *testing.T) {
+
+	cases := []struct {
+		name   string
+		in     string
+		expErr error
+	}{
+		{"not an object", `[]`, ErrScanNotObject{'['}},
+		{"no colon", `{"a" 1}`, ErrUnexpected{':', '1'}},
+		{"no comma", `{"a":1 "b":2}`, ErrUnexpected{',', '"'}},
+		{"key isn't a string", `{a:1}`, ErrUnexpected{'"', 'a'}},
+		{"unclosed key", `{"a`, io.EOF},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			j := New(sread(tc.in))
+			it, err := j.IterObject()
+			for err == nil {
+				var more bool
+				more, err = it.Next()
+				if !more {
+					break
+				}
+				err = j.Skip()
+			}
+			assert.Equal(t, tc.expErr, err)
+		})
+	}
+}
+
+func TestScanForNextKeyValue(t *testing.T) {
+	j := New(sread(`{"x":1, "y":{"x":2}, "x" : "three",` + "\n" + `"x":[4]} 5`))
+	j.chunkSize = 3
+
+	var values []string
+	found, err := j.ScanForKeyValue("x")
+	for found && err == nil {
+		b := strings.Builder{}
+		_, err = j.WriteCurrentTo(&b, true)
+		assert.NoError(t, err)
+		values = append(values, b.String())
+
+		found, err = j.ScanForNextKeyValue("x")
+	}
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", `"three"`, "[4]"}, values, "values")
+
+	c, err := j.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, byte('5'), c, "moved past the object")
+}
+
 func TestSaneValueStart(t *testing.T) {
 
 	cases := []struct {
@@ -438,8 +703,7 @@ func TestSaneValueStart(t *testing.T) {
 		{'n', true},
 		{'x', false},
 		{'f', true},
-		// JSON numbers can't actually start with a 0:
-		{'0', false},
+		{'0', true},
 		{'1', true},
 		{'2', true},
 		{'3', true},
@@ -463,3 +727,82 @@ func TestSaneValueStart(t *testing.T) {
 		})
 	}
 }
+
+func TestPosition(t *testing.T) {
+	j := New(sread("[1,\n22,\r\n\n  333, \"x\ny\"]"))
+	j.chunkSize = 4
+
+	assert.Equal(t, Position{Offset: 0, Line: 1, Column: 1}, j.Position(), "before we start")
+
+	it, err := j.IterArray()
+	assert.NoError(t, err)
+
+	var got []Position
+	for {
+		more, err := it.Next()
+		assert.NoError(t, err)
+		if !more {
+			break
+		}
+		got = append(got, j.Position())
+		assert.NoError(t, j.Skip())
+	}
+
+	assert.Equal(t, []Position{
+		{Offset: 1, Line: 1, Column: 2},
+		{Offset: 4, Line: 2, Column: 1},
+		{Offset: 12, Line: 4, Column: 3},
+		{Offset: 17, Line: 4, Column: 8},
+	}, got)
+
+	_, err = j.Next()
+	assert.Equal(t, io.EOF, err)
+	assert.Equal(t, Position{Offset: 23, Line: 5, Column: 4}, j.Position(), "at the end")
+}
+
+func TestLocate(t *testing.T) {
+	j := New(sread("[1,\n2 3]"))
+	j.chunkSize = 3
+
+	_, err := j.IterArray()
+	assert.NoError(t, err)
+	assert.NoError(t, j.Skip())
+	c, err := j.Next()
+	assert.NoError(t, err)
+	j.MoveOff()
+	assert.Equal(t, byte(','), c)
+	assert.NoError(t, j.Skip())
+
+	c, err = j.Next()
+	assert.NoError(t, err)
+	assert.Equal(t, byte('3'), c)
+
+	problem := fmt.Errorf("unexpected %c", c)
+	located := j.Locate(problem)
+	assert.Equal(t, ErrAt{Position{Offset: 6, Line: 2, Column: 3}, problem, Snippet{"2 3]", 2}}, located)
+	assert.EqualError(t, located, "unexpected 3, at line 2, column 3 (byte offset 6)")
+	assert.True(t, errors.Is(located, problem), "unwraps")
+	wrapped := fmt.Errorf("wrapped: %w", located)
+	assert.Equal(t, wrapped, j.Locate(wrapped), "only located once")
+	assert.Nil(t, j.Locate(nil))
+}
+
+func TestLocateInvalid(t *testing.T) {
+	j := New(sread("[1,\n 2,\n 3x]"))
+	j.chunkSize = 4
+	j.Strict()
+
+	_, err := j.IterArray()
+	assert.NoError(t, err)
+	for err == nil {
+		err = j.Skip()
+		if err == nil {
+			_, err = j.Next()
+			j.MoveOff()
+		}
+	}
+
+	var at ErrAt
+	assert.True(t, errors.As(j.Locate(err), &at))
+	assert.Equal(t, Position{Offset: 10, Line: 3, Column: 3}, at.Position)
+}
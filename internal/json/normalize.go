@@ -0,0 +1,52 @@
+package json
+
+import "io"
+
+// NormalizeNumbers wraps w so that numbers in the JSON written to it come out
+// in one form: exponents get a lower case e and lose any + sign, so 1E+5
+// becomes 1e5. Everything else, including strings, is left alone.
+func NormalizeNumbers(w io.Writer) io.Writer {
+	return &numberNormalizer{w: w}
+}
+
+type numberNormalizer struct {
+	w       io.Writer
+	inStr   bool
+	escaped bool
+	buf     []byte
+}
+
+func (n *numberNormalizer) Write(p []byte) (int, error) {
+	n.buf = n.buf[:0]
+
+	for _, b := range p {
+		if n.inStr {
+			if n.escaped {
+				n.escaped = false
+			} else if b == '\\' {
+				n.escaped = true
+			} else if b == '"' {
+				n.inStr = false
+			}
+		} else {
+			// outside of strings E and + can only be part of a
+			// number's exponent:
+			switch b {
+			case '"':
+				n.inStr = true
+			case 'E':
+				b = 'e'
+			case '+':
+				continue
+			}
+		}
+
+		n.buf = append(n.buf, b)
+	}
+
+	_, err := n.w.Write(n.buf)
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
@@ -0,0 +1,35 @@
+package json
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeNumbers(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []string
+		exp  string
+	}{
+		{"nothing to do", []string{`[1, -0.5, 1e5]`}, `[1, -0.5, 1e5]`},
+		{"upper case exponent", []string{`1E5`}, `1e5`},
+		{"plus sign", []string{`[1e+5, 2.5E+05, 3e-5]`}, `[1e5, 2.5e05, 3e-5]`},
+		{"strings left alone", []string{`{"E+":"1E+5 \"E+\" \\", "x":1E+5}`}, `{"E+":"1E+5 \"E+\" \\", "x":1e5}`},
+		{"split across writes", []string{`["a`, `E+", 1E`, `+5, "\`, `"E+"]`}, `["aE+", 1e5, "\"E+"]`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := strings.Builder{}
+			w := NormalizeNumbers(&out)
+			for _, in := range tc.in {
+				n, err := w.Write([]byte(in))
+				assert.NoError(t, err)
+				assert.Equal(t, len(in), n, "n")
+			}
+			assert.Equal(t, tc.exp, out.String())
+		})
+	}
+}
@@ -0,0 +1,99 @@
+package json
+
+import "fmt"
+
+// number checks the grammar of a JSON number, a byte at a time.
+type number struct {
+	at numberPart
+}
+
+type numberPart int
+
+const (
+	numStart numberPart = iota
+	numMinus
+	numZero
+	numInt
+	numDot
+	numFrac
+	numE
+	numESign
+	numExp
+)
+
+// next takes the next byte, false if it isn't part of the number, in which
+// case the number should be checked with end. Errors describe what's wrong
+// with the number, but not that it's a number.
+func (n *number) next(b byte) (bool, error) {
+	digit := b >= '0' && b <= '9'
+
+	switch n.at {
+	case numStart, numMinus:
+		switch {
+		case b == '-' && n.at == numStart:
+			n.at = numMinus
+		case b == '0':
+			n.at = numZero
+		case digit:
+			n.at = numInt
+		default:
+			return false, errNumber(b, "a digit")
+		}
+	case numZero, numInt:
+		switch {
+		case b == '.':
+			n.at = numDot
+		case b == 'e' || b == 'E':
+			n.at = numE
+		case digit && n.at == numZero:
+			return false, fmt.Errorf("it has a leading zero")
+		case !digit:
+			return false, nil
+		}
+	case numDot:
+		if !digit {
+			return false, errNumber(b, "a digit after the decimal point")
+		}
+		n.at = numFrac
+	case numFrac:
+		switch {
+		case b == 'e' || b == 'E':
+			n.at = numE
+		case !digit:
+			return false, nil
+		}
+	case numE:
+		switch {
+		case b == '+' || b == '-':
+			n.at = numESign
+		case digit:
+			n.at = numExp
+		default:
+			return false, errNumber(b, "an exponent")
+		}
+	case numESign:
+		if !digit {
+			return false, errNumber(b, "an exponent")
+		}
+		n.at = numExp
+	case numExp:
+		if !digit {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// end checks the number is complete.
+func (n *number) end() error {
+	switch n.at {
+	case numZero, numInt, numFrac, numExp:
+		return nil
+	}
+	return fmt.Errorf("it ends too soon")
+}
+
+func errNumber(found byte, expected string) error {
+	return fmt.Errorf("expected %s but found %q", expected, found)
+}
@@ -0,0 +1,154 @@
+package json
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// Capture keeps a copy of everything from the cursor on in w, until
+// EndCapture. It's so that we still have the raw bytes of a value if it turns
+// out to be bad, see SkipBad.
+func (j *JSON) Capture(w *bytes.Buffer) {
+	j.capture = w
+	j.captureFrom = j.idx
+	if j.captureFrom < 0 {
+		j.captureFrom = 0
+	}
+}
+
+// EndCapture stops Capture at the cursor.
+func (j *JSON) EndCapture() {
+	j.flushCapture()
+	j.capture = nil
+}
+
+// flushCapture copies what's been captured so far out of buf.
+func (j *JSON) flushCapture() {
+	if j.capture == nil {
+		return
+	}
+
+	end := j.idx
+	if end > j.bytes {
+		end = j.bytes
+	}
+	if end > j.captureFrom {
+		j.capture.Write(j.buf[j.captureFrom:end])
+	}
+	j.captureFrom = end
+}
+
+// SkipBad skips the rest of a bad value in an array, leaving the cursor on the
+// ',' or ']' after it. The value should have been captured from its start
+// (see Capture), we need what we've been through of it to know where we are,
+// and the capture ends up with all of it. depth is how many containers deep
+// the array is (1 for a top level array) so that we can pick up being strict
+// from there.
+//
+// We can't tell where a bad value ends for sure, we go by the brackets and
+// strings in it, so a badly broken value can take more than itself with it.
+func (j *JSON) SkipBad(depth int) error {
+	if j.capture == nil {
+		return ErrInternal{errNoCapture}
+	}
+
+	v := j.validator
+	if v != nil && !v.canResync(depth) {
+		return ErrInternal{errNoResync}
+	}
+
+	// we're going over what a problem hid from us:
+	j.validator = nil
+	j.invalid = nil
+	j.bytes = j.read
+	defer func() {
+		j.validator = v
+	}()
+
+	j.flushCapture()
+	s := skipper{}
+	for _, b := range j.capture.Bytes() {
+		s.next(b)
+	}
+
+	for {
+		ok, err := j.data()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return io.EOF
+		}
+
+		for ; j.idx < j.bytes; j.idx++ {
+			if s.next(j.buf[j.idx]) {
+				j.EndCapture()
+				if v != nil {
+					v.resync(j.base+int64(j.idx), depth)
+					var n int
+					n, j.invalid = v.check(j.buf[j.idx:j.read])
+					j.bytes = j.idx + n
+				}
+				return nil
+			}
+		}
+		j.flushCapture()
+	}
+}
+
+// skipper finds the end of a value by its brackets and strings, see SkipBad.
+type skipper struct {
+	// stack of the brackets we're inside of
+	stack    []byte
+	inString bool
+	escaped  bool
+}
+
+// next is true when b is the ',' or ']' after the value.
+func (s *skipper) next(b byte) bool {
+	if s.inString {
+		switch {
+		case s.escaped:
+			s.escaped = false
+		case b == '\\':
+			s.escaped = true
+		case b == '"':
+			s.inString = false
+		}
+		return false
+	}
+
+	switch b {
+	case '"':
+		s.inString = true
+	case '[', '{':
+		s.stack = append(s.stack, b)
+	case ']', '}':
+		if len(s.stack) == 0 {
+			return b == ']'
+		}
+		s.close(b)
+	case ',':
+		return len(s.stack) == 0
+	}
+	return false
+}
+
+// close goes back to the bracket that closer closes, taking any left open
+// inside of it to be mistakes. If there isn't one we take it to be the wrong
+// closer for the innermost bracket, e.g [1, 2}.
+func (s *skipper) close(closer byte) {
+	for i := len(s.stack) - 1; i >= 0; i-- {
+		if closerFor(s.stack[i]) == closer {
+			s.stack = s.stack[:i]
+			return
+		}
+	}
+	s.stack = s.stack[:len(s.stack)-1]
+}
+
+var (
+	errNoCapture = errors.New("skipping a bad value we didn't capture")
+	errNoResync  = errors.New("skipping a bad value we can't find the array of")
+)
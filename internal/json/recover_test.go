@@ -0,0 +1,114 @@
+package json
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSkipBad(t *testing.T) {
+	cases := []struct {
+		name   string
+		in     string
+		strict bool
+		// exp is the good values, and expBad what we skipped
+		exp    []string
+		expBad []string
+	}{
+		{
+			name:   "bad number",
+			in:     `[1, 1.e5, 2]`,
+			exp:    []string{"1", "2"},
+			expBad: []string{"1.e5"},
+		},
+		{
+			name:   "bad literal at the end",
+			in:     `[1, truex]`,
+			exp:    []string{"1"},
+			expBad: []string{"truex"},
+		},
+		{
+			name:   "strict",
+			in:     `[{"a":1}, {"a" 2}, {"a":3}]`,
+			strict: true,
+			exp:    []string{`{"a":1}`, `{"a":3}`},
+			expBad: []string{`{"a" 2}`},
+		},
+		{
+			name:   "strict, separators in strings",
+			in:     `[{"a":"x,]"  "b"}, {"a":"],"}]`,
+			strict: true,
+			exp:    []string{`{"a":"],"}`},
+			expBad: []string{`{"a":"x,]"  "b"}`},
+		},
+		{
+			name:   "strict, mismatched brackets",
+			in:     `[[1, 2}, {"a": [3}, 4]`,
+			strict: true,
+			exp:    []string{"4"},
+			expBad: []string{"[1, 2}", `{"a": [3}`},
+		},
+		{
+			name:   "strict, problems after skipping",
+			in:     `[+1, 2, -, 3]`,
+			strict: true,
+			exp:    []string{"2", "3"},
+			expBad: []string{"+1", "-"},
+		},
+	}
+
+	for _, tc := range cases {
+		for _, chunk := range []int{1, 3, 4096} {
+			t.Run(fmt.Sprintf("%s, chunks of %d", tc.name, chunk), func(t *testing.T) {
+				j := New(bytes.NewBufferString(tc.in))
+				j.chunkSize = chunk
+				if tc.strict {
+					j.Strict()
+				}
+
+				_, err := j.Next()
+				assert.NoError(t, err)
+				j.MoveOff()
+
+				var good, bad []string
+				for {
+					raw := bytes.Buffer{}
+					c, err := j.Next()
+					j.Capture(&raw)
+
+					if err == nil {
+						b := bytes.Buffer{}
+						_, err = j.WriteCurrentTo(&b, true)
+						if err == nil {
+							good = append(good, b.String())
+							c, err = j.Next()
+						}
+					}
+
+					if err != nil {
+						if serr := j.SkipBad(1); !assert.NoError(t, serr, "skipping %v", err) {
+							return
+						}
+						bad = append(bad, string(bytes.TrimSpace(raw.Bytes())))
+						c, err = j.Next()
+					}
+
+					assert.NoError(t, err)
+					j.MoveOff()
+					if c != ',' {
+						break
+					}
+				}
+
+				assert.Equal(t, tc.exp, good, "good values")
+				assert.Equal(t, tc.expBad, bad, "bad values")
+
+				_, err = j.Next()
+				assert.Equal(t, io.EOF, err, "nothing after the array")
+			})
+		}
+	}
+}
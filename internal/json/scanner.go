@@ -5,18 +5,23 @@ import (
 )
 
 type state struct {
-	in            byte
-	closer        byte
-	closerBalance int
-	last          byte
-	lastNotWs     byte
-	inStr         bool
-	key           bool
-	open          bool
-	seek          struct {
+	in     byte
+	closer byte
+	// depth is how many containers deep inside what we're scanning we are
+	depth     int
+	lastNotWs byte
+	inStr     bool
+	// escaped is true when the last byte in a string was a backslash that
+	// escapes the byte after it
+	escaped bool
+	key     bool
+	open    bool
+	seek    struct {
 		keyName  []byte
 		cursor   int
 		matching bool
+		// keys are matched as they'd be decoded
+		unescaper unescaper
 	}
 	seeking   bool
 	seekFound bool
@@ -53,88 +58,113 @@ func (s *state) scan(chunk []byte, idx, max int) (int, error) {
 	for ; idx < max; idx++ {
 		b := chunk[idx]
 
+		if s.inStr {
+			if s.escaped {
+				// whatever follows a backslash can't end the string
+				s.escaped = false
+			} else if b == '\\' {
+				s.escaped = true
+			} else if b == '"' {
+				if s.endString() {
+					return idx, nil
+				}
+				continue
+			}
+
+			if s.seeking && s.key && s.seek.matching {
+				s.matchKey(b)
+			}
+			continue
+		}
+
 		// is whitespace?
 		if b <= ' ' {
 			if b == ' ' || b == '\t' || b == '\r' || (s.seeking && b == '\n') {
-				s.last = b
 				continue
 			}
 
 			// FUTURE: better way to communicate skips
-			if !s.seeking && b == '\n' && !s.inStr {
+			if !s.seeking && b == '\n' {
 				return idx, nil
 			}
 		}
 
-		// start or end of a string:
-		if b == '"' {
-			if s.inStr && s.last != '\\' {
-				// end of string.
-				s.inStr = false
-				if s.in == '{' && s.key {
-					s.key = false
-					if s.seeking {
-						if s.closerBalance == 0 {
-							if s.seek.matching && s.seek.cursor == len(s.seek.keyName) {
-								s.seeking = false
-								s.seekFound = true
-								return idx, nil
-							}
-						}
-						s.last = b
-					}
-				}
-				if s.closer == '"' {
-					s.last = b
-					s.open = false
-					return idx, nil
-				}
-			} else if !s.inStr {
-				// start of string
-				s.inStr = true
-				if s.in == '{' && !s.key && s.lastNotWs != ':' {
-					s.key = true
-				}
-				if s.seeking && s.closerBalance == 0 && s.key {
-					s.seek.cursor = 0
-					s.seek.matching = true
-				}
+		switch b {
+		case '"':
+			// start of string
+			s.inStr = true
+			if s.in == '{' && s.depth == 0 && s.lastNotWs != ':' {
+				s.key = true
 			}
-		} else if s.seeking && s.key && s.seek.matching {
-			// is this the object key we were looking for?
-			if s.seek.cursor >= len(s.seek.keyName) {
-				s.seek.matching = false
-			} else if s.seek.keyName[s.seek.cursor] != b {
-				s.seek.matching = false
-			} else {
-				s.seek.cursor++
+			if s.seeking && s.key {
+				s.seek.cursor = 0
+				s.seek.matching = true
+				s.seek.unescaper = unescaper{}
 			}
-		}
-
-		// possibly the end of whatever we are scanning
-		if !s.inStr {
-			if b == s.closer {
-				if s.closerBalance == 0 {
-					s.open = false
-					s.last = b
-					s.lastNotWs = b
-					return idx, nil
-				} else {
-					s.closerBalance--
-				}
-
-			} else if b == s.in {
-				s.closerBalance++
+		case s.closer:
+			if s.depth == 0 {
+				// the end of whatever we are scanning
+				s.open = false
+				s.lastNotWs = b
+				return idx, nil
 			}
+			s.depth--
+		case '{', '[':
+			s.depth++
+		case '}', ']':
+			s.depth--
 		}
 
-		s.last = b
 		s.lastNotWs = b
 	}
 
 	return max, nil
 }
 
+// endString deals with the closing quote of a string, true if that's the end
+// of the scan.
+func (s *state) endString() bool {
+	s.inStr = false
+	s.lastNotWs = '"'
+
+	if s.key {
+		s.key = false
+		if s.seeking && s.seek.matching {
+			out, n := s.seek.unescaper.end()
+			s.matchBytes(out[:n])
+		}
+		if s.seeking && s.seek.matching && s.seek.cursor == len(s.seek.keyName) {
+			s.seeking = false
+			s.seekFound = true
+			return true
+		}
+	}
+
+	if s.closer == '"' {
+		s.open = false
+		return true
+	}
+
+	return false
+}
+
+// matchKey checks the next byte of a key against the one we're seeking,
+// decoding any escapes as we go.
+func (s *state) matchKey(b byte) {
+	out, n := s.seek.unescaper.next(b)
+	s.matchBytes(out[:n])
+}
+
+func (s *state) matchBytes(bs []byte) {
+	for _, b := range bs {
+		if s.seek.cursor >= len(s.seek.keyName) || s.seek.keyName[s.seek.cursor] != b {
+			s.seek.matching = false
+			return
+		}
+		s.seek.cursor++
+	}
+}
+
 // TODO: we also have ErrBadJSONValue :)
 type ErrBadJSONValue struct {
 	Char byte
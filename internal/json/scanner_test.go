@@ -148,3 +148,123 @@ func TestFutileScan(t *testing.T) {
 	assert.False(t, s.open)
 	assert.NoError(t, err)
 }
+
+func TestScanEscapes(t *testing.T) {
+	cases := []struct {
+		name string
+		// in follows the opening quote of a string
+		in  string
+		exp string
+	}{
+		{"no escapes", `abc"`, `abc"`},
+		{"quote", `a\"b"`, `a\"b"`},
+		{"backslash", `a\\b"`, `a\\b"`},
+		{"ends with a backslash", `C:\\"`, `C:\\"`},
+		{"ends with two backslashes", `\\\\" x"`, `\\\\"`},
+		{"backslash then quote", `\\\"x"`, `\\\"x"`},
+		{"run of backslashes", `\\\\\\\"\\"`, `\\\\\\\"\\"`},
+		{"solidus", `\/"`, `\/"`},
+		{"backspace", `\b"`, `\b"`},
+		{"form feed", `\f"`, `\f"`},
+		{"newline", `\n"`, `\n"`},
+		{"carriage return", `\r"`, `\r"`},
+		{"tab", `\t"`, `\t"`},
+		{"unicode", `\u0022"`, `\u0022"`},
+		{"unicode backslash", `\u005c"`, `\u005c"`},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewScanState('"')
+			buf := []byte(tc.in)
+			pos, err := s.scan(buf, 0, len(buf))
+			assert.NoError(t, err)
+			assert.False(t, s.open, "closed")
+			assert.Equal(t, tc.exp, string(buf[:pos+1]), "closed in the right place")
+		})
+
+		t.Run(tc.name+", a byte at a time", func(t *testing.T) {
+			s := NewScanState('"')
+			buf := []byte(tc.in)
+			pos := 0
+			for ; pos < len(buf); pos++ {
+				_, err := s.scan(buf[pos:pos+1], 0, 1)
+				assert.NoError(t, err)
+				if !s.open {
+					break
+				}
+			}
+			assert.False(t, s.open, "closed")
+			assert.Equal(t, tc.exp, string(buf[:pos+1]), "closed in the right place")
+		})
+	}
+}
+
+func TestScanForPastEscapes(t *testing.T) {
+	s := NewScanState('{')
+	s.seekFor("z")
+	buf := []byte(`"p":"C:\\", "q\\":1, "z":2}`)
+
+	pos, err := s.scan(buf, 0, len(buf))
+	assert.NoError(t, err)
+	assert.True(t, s.seekFound, "found")
+	assert.Equal(t, `":2}`, string(buf[pos:]), "stopped in the correct place")
+}
+
+func TestScanForKeysOnly(t *testing.T) {
+	s := NewScanState('{')
+	s.seekFor("z")
+	buf := []byte(`"a":["z", {"z":1}], "b":"z", "z b":3, "z":2}`)
+
+	pos, err := s.scan(buf, 0, len(buf))
+	assert.NoError(t, err)
+	assert.True(t, s.seekFound, "found")
+	assert.Equal(t, `":2}`, string(buf[pos:]), "stopped in the correct place")
+}
+
+func TestScanForEscapedKey(t *testing.T) {
+	cases := []struct {
+		name  string
+		key   string
+		in    string
+		found bool
+	}{
+		{"unicode escape", "café", `"cafe":1, "caf\u00e9":2}`, true},
+		{"every char escaped", "id", `"\u0069\u0064":2}`, true},
+		{"surrogate pair", "😀", `"\ud83d":1, "\ud83d\ude00":2}`, true},
+		{"escaped quote", `a"b`, `"a\"b":2}`, true},
+		{"escaped backslash", `a\b`, `"a\\b":2}`, true},
+		{"raw utf-8", "café", `"café":2}`, true},
+		{"escape makes it longer", "a", `"ab":1}`, false},
+		{"not the escaped value", "u0069", `"\u0069":1}`, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			s := NewScanState('{')
+			s.seekFor(tc.key)
+			buf := []byte(tc.in)
+
+			pos, err := s.scan(buf, 0, len(buf))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.found, s.seekFound, "found")
+			if tc.found {
+				assert.Equal(t, `":2}`, string(buf[pos:]), "stopped in the correct place")
+			}
+		})
+	}
+}
+
+func TestScanForEscapedKeyDoesNotAllocate(t *testing.T) {
+	buf := []byte(`"caf\u00e9x":1, "\ud83d\ude00":[{"caf\u00e9": 2}], "caf\u00e9":3}`)
+	s := NewScanState('{')
+	s.seekFor("café")
+
+	allocs := testing.AllocsPerRun(100, func() {
+		*s = state{in: '{', closer: '}', open: true, seek: s.seek, seeking: true}
+		_, _ = s.scan(buf, 0, len(buf))
+	})
+
+	assert.True(t, s.seekFound, "found")
+	assert.Zero(t, allocs, "allocations")
+}
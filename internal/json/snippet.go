@@ -0,0 +1,128 @@
+package json
+
+import (
+	"bytes"
+	"strings"
+	"unicode/utf8"
+)
+
+// how much of the input we show either side of a problem
+const (
+	snippetBefore = 60
+	snippetAfter  = 20
+)
+
+// ring keeps the last few bytes from before the current chunk, so we can show
+// what led up to a problem even when we can't go back and read it again.
+type ring struct {
+	buf [snippetBefore]byte
+	at  int
+	n   int
+}
+
+func (r *ring) write(p []byte) {
+	if len(p) > len(r.buf) {
+		p = p[len(p)-len(r.buf):]
+	}
+
+	for _, b := range p {
+		r.buf[r.at] = b
+		r.at = (r.at + 1) % len(r.buf)
+		if r.n < len(r.buf) {
+			r.n++
+		}
+	}
+}
+
+// appendTo appends what's in the ring to dst, oldest first.
+func (r *ring) appendTo(dst []byte) []byte {
+	start := (r.at - r.n + len(r.buf)) % len(r.buf)
+	if start+r.n <= len(r.buf) {
+		return append(dst, r.buf[start:start+r.n]...)
+	}
+	dst = append(dst, r.buf[start:]...)
+	return append(dst, r.buf[:r.at]...)
+}
+
+// Snippet is an excerpt of the input around a problem.
+type Snippet struct {
+	// Text from around the problem, on the same line
+	Text string
+	// Marker is where the problem is in Text, in characters
+	Marker int
+}
+
+// String shows the text with a caret under the problem, over two lines.
+func (s Snippet) String() string {
+	if s.Text == "" && s.Marker == 0 {
+		return ""
+	}
+
+	b := strings.Builder{}
+	b.WriteString(s.Text)
+	b.WriteByte('\n')
+
+	// keep any tabs so the caret lines up:
+	i := 0
+	for _, r := range s.Text {
+		if i == s.Marker {
+			break
+		}
+		if r == '\t' {
+			b.WriteByte('\t')
+		} else {
+			b.WriteByte(' ')
+		}
+		i++
+	}
+	for ; i < s.Marker; i++ {
+		b.WriteByte(' ')
+	}
+	b.WriteString("^\n")
+
+	return b.String()
+}
+
+// snippetAt gives us a snippet of the input around idx in the current chunk.
+func (j *JSON) snippetAt(idx int) Snippet {
+	before := j.recent.appendTo(nil)
+	before = append(before, j.buf[:idx]...)
+	if i := bytes.LastIndexAny(before, "\r\n"); i >= 0 {
+		before = before[i+1:]
+	}
+
+	ellipsis := ""
+	if len(before) > snippetBefore {
+		before = before[len(before)-snippetBefore:]
+		// don't start part way through a character:
+		for len(before) > 0 && !utf8.RuneStart(before[0]) {
+			before = before[1:]
+		}
+		ellipsis = "..."
+	}
+
+	after := j.buf[idx:j.read]
+	if i := bytes.IndexAny(after, "\r\n"); i >= 0 {
+		after = after[:i]
+	}
+	if len(after) > snippetAfter {
+		after = after[:snippetAfter]
+	}
+
+	start := ellipsis + printable(before)
+	return Snippet{
+		Text:   start + printable(after),
+		Marker: utf8.RuneCountInString(start),
+	}
+}
+
+// printable makes sure input is fit to print.
+func printable(b []byte) string {
+	s := strings.ToValidUTF8(string(b), "?")
+	return strings.Map(func(r rune) rune {
+		if r < ' ' && r != '\t' {
+			return ' '
+		}
+		return r
+	}, s)
+}
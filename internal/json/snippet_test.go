@@ -0,0 +1,116 @@
+package json
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRing(t *testing.T) {
+	r := ring{}
+	assert.Equal(t, "", string(r.appendTo(nil)), "empty")
+
+	r.write([]byte("abc"))
+	assert.Equal(t, "abc", string(r.appendTo(nil)))
+
+	long := strings.Repeat("x", snippetBefore-2) + "123"
+	r.write([]byte(long))
+	assert.Equal(t, long[1:], string(r.appendTo(nil)), "wrapped around")
+
+	r.write([]byte("45"))
+	assert.Equal(t, long[3:]+"45", string(r.appendTo(nil)), "wrapped around again")
+}
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("7", 100)
+
+	cases := []struct {
+		name  string
+		in    string
+		chunk int
+		// at is the offset of the problem
+		at  int
+		exp Snippet
+	}{
+		{
+			name:  "simple",
+			in:    `[1, 2, x, 4]`,
+			chunk: 64,
+			at:    7,
+			exp:   Snippet{"[1, 2, x, 4]", 7},
+		},
+		{
+			name:  "from earlier chunks",
+			in:    `[1, 2, x, 4]`,
+			chunk: 3,
+			at:    7,
+			exp:   Snippet{"[1, 2, x,", 7},
+		},
+		{
+			name:  "just the line it's on",
+			in:    "[1,\n 2 x,\r\n 4]",
+			chunk: 5,
+			at:    7,
+			exp:   Snippet{" 2 x,", 3},
+		},
+		{
+			name:  "long line",
+			in:    "[" + long + "x" + long + "]",
+			chunk: 50,
+			at:    101,
+			exp:   Snippet{"..." + long[:snippetBefore] + "x" + long[:snippetAfter-1], snippetBefore + 3},
+		},
+		{
+			name:  "at the end",
+			in:    `[1, 2`,
+			chunk: 2,
+			at:    5,
+			exp:   Snippet{"[1, 2", 5},
+		},
+		{
+			name:  "unicode",
+			in:    `["héllo", x]`,
+			chunk: 3,
+			at:    11,
+			exp:   Snippet{`["héllo", x`, 10},
+		},
+		{
+			name:  "control characters",
+			in:    "[\"a\x01\", \tx]",
+			chunk: 64,
+			at:    8,
+			exp:   Snippet{"[\"a \", \tx]", 8},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			j := New(sread(tc.in))
+			j.chunkSize = tc.chunk
+
+			// read up to the problem:
+			for j.base+int64(j.bytes) < int64(tc.at) || j.bytes == 0 {
+				j.idx = j.bytes
+				more, err := j.data()
+				assert.NoError(t, err)
+				if !more {
+					break
+				}
+			}
+			j.idx = tc.at - int(j.base)
+
+			assert.Equal(t, tc.exp, j.snippetAt(j.idx))
+		})
+	}
+}
+
+func TestSnippetString(t *testing.T) {
+	assert.Equal(t, "[1, x]\n    ^\n", Snippet{"[1, x]", 4}.String())
+	assert.Equal(t, "\t[1,\tx]\n\t   \t^\n", Snippet{"\t[1,\tx]", 5}.String(), "keeps tabs")
+	assert.Equal(t, "[é, x]\n    ^\n", Snippet{"[é, x]", 4}.String(), "in characters")
+	assert.Equal(t, "[1\n  ^\n", Snippet{"[1", 2}.String(), "past the end")
+	assert.Equal(t, "", Snippet{}.String(), "nothing to show")
+	assert.Equal(t, "", fmt.Sprint(Snippet{}))
+}
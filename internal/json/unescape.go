@@ -0,0 +1,120 @@
+package json
+
+import (
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// unescaper decodes the escapes in a JSON string as it's fed the string a
+// byte at a time, without allocating. It's lenient, anything it can't make
+// sense of is passed through or becomes utf8.RuneError.
+type unescaper struct {
+	escape bool
+	// hex is how many digits of a \u escape are still to come
+	hex int
+	r   rune
+	// high is the first half of a surrogate pair, waiting for the second
+	high rune
+}
+
+// next takes the next byte of the string (less its quotes), giving back
+// whatever that decodes to, which may be nothing yet.
+func (u *unescaper) next(b byte) (out [8]byte, n int) {
+	if u.hex > 0 {
+		if v, ok := unhex(b); ok {
+			u.r = u.r<<4 | v
+			u.hex--
+			if u.hex == 0 {
+				n = u.decoded(u.r, out[:])
+			}
+			return out, n
+		}
+
+		// that wasn't a \u escape after all:
+		u.hex = 0
+		n = u.decoded(utf8.RuneError, out[:])
+	} else if u.escape {
+		u.escape = false
+		if b == 'u' {
+			u.hex = 4
+			u.r = 0
+			return out, n
+		}
+		b = unescape(b)
+	} else if b == '\\' {
+		u.escape = true
+		return out, n
+	}
+
+	n += u.flush(out[n:])
+	out[n] = b
+	return out, n + 1
+}
+
+// end is called at the end of the string, giving back anything still waiting
+// to be decoded.
+func (u *unescaper) end() (out [8]byte, n int) {
+	if u.hex > 0 {
+		u.hex = 0
+		n = u.decoded(utf8.RuneError, out[:])
+	}
+	n += u.flush(out[n:])
+	return out, n
+}
+
+// decoded deals with a rune from a \u escape, which may be half of a
+// surrogate pair.
+func (u *unescaper) decoded(r rune, dst []byte) int {
+	if u.high != 0 && r >= 0xdc00 && r < 0xe000 {
+		r = utf16.DecodeRune(u.high, r)
+		u.high = 0
+		return utf8.EncodeRune(dst, r)
+	}
+
+	n := u.flush(dst)
+	if r >= 0xd800 && r < 0xdc00 {
+		u.high = r
+		return n
+	}
+
+	// (a lone low surrogate is encoded as utf8.RuneError)
+	return n + utf8.EncodeRune(dst[n:], r)
+}
+
+// flush gives up on a high surrogate that never got its other half.
+func (u *unescaper) flush(dst []byte) int {
+	if u.high == 0 {
+		return 0
+	}
+	u.high = 0
+	return utf8.EncodeRune(dst, utf8.RuneError)
+}
+
+func unescape(b byte) byte {
+	switch b {
+	case 'b':
+		return '\b'
+	case 'f':
+		return '\f'
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	}
+	// which covers \", \\ and \/
+	return b
+}
+
+func unhex(b byte) (rune, bool) {
+	switch {
+	case '0' <= b && b <= '9':
+		return rune(b - '0'), true
+	case 'a' <= b && b <= 'f':
+		return rune(b - 'a' + 10), true
+	case 'A' <= b && b <= 'F':
+		return rune(b - 'A' + 10), true
+	}
+	return 0, false
+}
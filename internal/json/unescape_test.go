@@ -0,0 +1,46 @@
+package json
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnescaper(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		exp  string
+	}{
+		{"plain", `abc`, "abc"},
+		{"simple escapes", `\"\\\/\b\f\n\r\t`, "\"\\/\b\f\n\r\t"},
+		{"unicode", `caf\u00e9`, "café"},
+		{"unicode upper case hex", `caf\u00E9`, "café"},
+		{"unicode ascii", `\u0069\u0064`, "id"},
+		{"raw utf-8 left alone", `café`, "café"},
+		{"surrogate pair", `\ud83d\ude00!`, "😀!"},
+		{"lone high surrogate", `\ud83dx`, "�x"},
+		{"lone high surrogate at the end", `\ud83d`, "�"},
+		{"high surrogate then an escape", `\ud83d\n`, "�\n"},
+		{"high surrogate then not a low one", `\ud83dA`, "�A"},
+		{"lone low surrogate", `\ude00`, "�"},
+		{"bad hex", `\u00zz`, "�zz"},
+		{"short unicode at the end", `\u00`, "�"},
+		{"unknown escape", `\x`, "x"},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			var u unescaper
+			var got []byte
+			for _, b := range []byte(tc.in) {
+				out, n := u.next(b)
+				got = append(got, out[:n]...)
+			}
+			out, n := u.end()
+			got = append(got, out[:n]...)
+
+			assert.Equal(t, tc.exp, string(got))
+		})
+	}
+}
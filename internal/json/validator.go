@@ -0,0 +1,317 @@
+package json
+
+import "fmt"
+
+// validator checks that what it's fed is valid JSON (RFC 8259), a chunk at a
+// time, so we can be strict without holding on to anything more than the
+// containers we're inside of. It accepts a stream of values.
+type validator struct {
+	// offset is how many bytes we've checked so far
+	offset int64
+	at     validatorState
+	// stack of the containers we're inside of
+	stack []byte
+	// key is true when the string we're in is an object key
+	key bool
+	// needSpace is true when the last top level value needs whitespace
+	// before another can follow it, e.g 1 2 rather than 12
+	needSpace bool
+	// hex is how many digits of a \u escape are still to come
+	hex int
+	// utf8 is how many continuation bytes of a character are still to
+	// come, and what range the next should be in
+	utf8   int
+	lo, hi byte
+	num    number
+	// literal is the true, false or null we're part way through
+	literal string
+	lit     int
+}
+
+type validatorState int
+
+const (
+	vTop validatorState = iota
+	vValue
+	vValueOrEnd
+	vKey
+	vKeyOrEnd
+	vColon
+	vAfter
+	vString
+	vEscape
+	vHex
+	vUTF8
+	vNumber
+	vLiteral
+)
+
+// check the next chunk, if there's a problem we also get the index of the
+// byte where it starts.
+func (v *validator) check(chunk []byte) (int, error) {
+	for i, b := range chunk {
+		err := v.next(b)
+		if err != nil {
+			return i, ErrInvalidJSON{v.offset, err.Error()}
+		}
+		v.offset++
+	}
+	return len(chunk), nil
+}
+
+// end checks that we didn't stop part way through a value.
+func (v *validator) end() error {
+	if v.at == vNumber {
+		err := v.num.end()
+		if err != nil {
+			return ErrInvalidJSON{v.offset, "bad number, " + err.Error()}
+		}
+		v.valueDone(true)
+	}
+
+	if v.at != vTop {
+		return ErrInvalidJSON{v.offset, problemEOF}
+	}
+	return nil
+}
+
+// canResync is true if we can pick up again in the array depth containers
+// deep, see resync.
+func (v *validator) canResync(depth int) bool {
+	return depth > 0 && depth <= len(v.stack) && v.stack[depth-1] == '['
+}
+
+// resync picks up checking again after a value in the array depth containers
+// deep, which has been skipped over, with the byte at offset.
+func (v *validator) resync(offset int64, depth int) {
+	*v = validator{
+		offset: offset,
+		at:     vAfter,
+		stack:  v.stack[:depth],
+	}
+}
+
+func (v *validator) next(b byte) error {
+	switch v.at {
+	case vString:
+		return v.stringByte(b)
+	case vEscape:
+		switch b {
+		case '"', '\\', '/', 'b', 'f', 'n', 'r', 't':
+			v.at = vString
+		case 'u':
+			v.at = vHex
+			v.hex = 4
+		default:
+			return fmt.Errorf("bad escape in string: \\%c", b)
+		}
+		return nil
+	case vHex:
+		if _, ok := unhex(b); !ok {
+			return fmt.Errorf("bad \\u escape in string, %q isn't a hex digit", b)
+		}
+		v.hex--
+		if v.hex == 0 {
+			v.at = vString
+		}
+		return nil
+	case vUTF8:
+		if b < v.lo || b > v.hi {
+			return fmt.Errorf("invalid UTF-8 in string")
+		}
+		v.lo, v.hi = 0x80, 0xbf
+		v.utf8--
+		if v.utf8 == 0 {
+			v.at = vString
+		}
+		return nil
+	case vNumber:
+		more, err := v.num.next(b)
+		if err == nil && !more {
+			err = v.num.end()
+		}
+		if err != nil {
+			return fmt.Errorf("bad number, %w", err)
+		}
+		if more {
+			return nil
+		}
+		v.valueDone(true)
+		return v.next(b)
+	case vLiteral:
+		if b != v.literal[v.lit] {
+			return fmt.Errorf("expected %s but found %q", v.literal, b)
+		}
+		v.lit++
+		if v.lit == len(v.literal) {
+			v.valueDone(true)
+		}
+		return nil
+	}
+
+	if isSpace(b) {
+		if v.at == vTop {
+			v.needSpace = false
+		}
+		return nil
+	}
+
+	switch v.at {
+	case vTop:
+		if v.needSpace {
+			return fmt.Errorf("expected whitespace between values but found %q", b)
+		}
+		return v.value(b)
+	case vValue:
+		return v.value(b)
+	case vValueOrEnd:
+		if b == ']' {
+			v.close()
+			return nil
+		}
+		return v.value(b)
+	case vKeyOrEnd, vKey:
+		if b == '}' && v.at == vKeyOrEnd {
+			v.close()
+			return nil
+		}
+		if b != '"' {
+			return fmt.Errorf("expected an object key but found %q", b)
+		}
+		v.at = vString
+		v.key = true
+	case vColon:
+		if b != ':' {
+			return fmt.Errorf("expected ':' but found %q", b)
+		}
+		v.at = vValue
+	case vAfter:
+		in := v.stack[len(v.stack)-1]
+		switch {
+		case b == ',' && in == '{':
+			v.at = vKey
+		case b == ',':
+			v.at = vValue
+		case b == closerFor(in):
+			v.close()
+		default:
+			return fmt.Errorf("expected ',' or '%c' but found %q", closerFor(in), b)
+		}
+	}
+
+	return nil
+}
+
+// value starts a value.
+func (v *validator) value(b byte) error {
+	switch b {
+	case '{':
+		v.stack = append(v.stack, b)
+		v.at = vKeyOrEnd
+	case '[':
+		v.stack = append(v.stack, b)
+		v.at = vValueOrEnd
+	case '"':
+		v.at = vString
+		v.key = false
+	case 't':
+		v.startLiteral("true")
+	case 'f':
+		v.startLiteral("false")
+	case 'n':
+		v.startLiteral("null")
+	default:
+		if b != '-' && (b < '0' || b > '9') {
+			return fmt.Errorf("expected a value but found %q", b)
+		}
+		v.num = number{}
+		v.at = vNumber
+		_, _ = v.num.next(b)
+	}
+	return nil
+}
+
+func (v *validator) startLiteral(l string) {
+	v.at = vLiteral
+	v.literal = l
+	v.lit = 1
+}
+
+func (v *validator) close() {
+	v.stack = v.stack[:len(v.stack)-1]
+	v.valueDone(false)
+}
+
+// valueDone moves on from a complete value.
+func (v *validator) valueDone(needSpace bool) {
+	if len(v.stack) > 0 {
+		v.at = vAfter
+		return
+	}
+	v.at = vTop
+	v.needSpace = needSpace
+}
+
+func (v *validator) stringByte(b byte) error {
+	switch {
+	case b == '"' && v.key:
+		v.at = vColon
+	case b == '"':
+		v.valueDone(false)
+	case b == '\\':
+		v.at = vEscape
+	case b < 0x20:
+		return fmt.Errorf("control character %#02x in string, it should be escaped", b)
+	case b < 0x80:
+	default:
+		return v.utf8Start(b)
+	}
+	return nil
+}
+
+// utf8Start checks the first byte of a multi-byte UTF-8 character, working
+// out what should follow it.
+func (v *validator) utf8Start(b byte) error {
+	v.lo, v.hi = 0x80, 0xbf
+
+	switch {
+	case b >= 0xc2 && b <= 0xdf:
+		v.utf8 = 1
+	case b == 0xe0:
+		v.utf8 = 2
+		v.lo = 0xa0
+	case b == 0xed:
+		// no surrogates
+		v.utf8 = 2
+		v.hi = 0x9f
+	case b >= 0xe1 && b <= 0xef:
+		v.utf8 = 2
+	case b == 0xf0:
+		v.utf8 = 3
+		v.lo = 0x90
+	case b >= 0xf1 && b <= 0xf3:
+		v.utf8 = 3
+	case b == 0xf4:
+		v.utf8 = 3
+		v.hi = 0x8f
+	default:
+		return fmt.Errorf("invalid UTF-8 in string")
+	}
+
+	v.at = vUTF8
+	return nil
+}
+
+// ErrInvalidJSON is what we find when checking JSON strictly.
+type ErrInvalidJSON struct {
+	// Offset of the byte where the problem is
+	Offset  int64
+	Problem string
+}
+
+func (e ErrInvalidJSON) Error() string {
+	return fmt.Sprintf("invalid JSON: %s", e.Problem)
+}
+
+// problemEOF is the problem when the input ends part way through a value.
+const problemEOF = "unexpected end of input"
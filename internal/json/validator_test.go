@@ -0,0 +1,113 @@
+package json
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidator(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		// expErr is blank for valid JSON
+		expErr string
+		// at is the offset of the problem
+		at int64
+	}{
+		{name: "empty", in: ``},
+		{name: "object", in: `{"a": [1, -2.5e+3, true, false, null, "x"], "b": {}}`},
+		{name: "nested arrays", in: `[[], [[]], [{}]]`},
+		{name: "stream", in: `{"a":1}` + "\n" + `{"a":2}[3]"x" 4 true`},
+		{name: "stream of scalars", in: "1\n2\ttrue null"},
+		{name: "escapes", in: `"\"\\\/\b\f\n\r\t\u00e9\uD83D\ude00"`},
+		{name: "utf-8", in: `"café 😀"`},
+		{name: "zero", in: `[0, -0, 0.5, 0e1, 10]`},
+		{name: "exponents", in: `[1e5, 1E5, 1e+5, 1e-5, 1.5E-05]`},
+		{name: "leading zero", in: `[01]`, expErr: "bad number, it has a leading zero", at: 2},
+		{name: "dots", in: `[1.....4]`, expErr: `bad number, expected a digit after the decimal point but found '.'`, at: 3},
+		{name: "double sign", in: `1e+-2`, expErr: `bad number, expected an exponent but found '-'`, at: 3},
+		{name: "plus", in: `[+1]`, expErr: `expected a value but found '+'`, at: 1},
+		{name: "bare minus", in: `[-]`, expErr: `bad number, expected a digit but found ']'`, at: 2},
+		{name: "number ends at the end", in: `1.`, expErr: "bad number, it ends too soon", at: 2},
+		{name: "trailing dot", in: `[1.]`, expErr: `bad number, expected a digit after the decimal point but found ']'`, at: 3},
+		{name: "bad literal", in: `[tru]`, expErr: `expected true but found ']'`, at: 4},
+		{name: "capital literal", in: `[True]`, expErr: `expected a value but found 'T'`, at: 1},
+		{name: "literal run on", in: `[truex]`, expErr: `expected ',' or ']' but found 'x'`, at: 5},
+		{name: "values run together", in: `1 2 truenull`, expErr: `expected whitespace between values but found 'n'`, at: 8},
+		{name: "bad escape", in: `"\x"`, expErr: `bad escape in string: \x`, at: 2},
+		{name: "bad unicode escape", in: `"\u00g0"`, expErr: `bad \u escape in string, 'g' isn't a hex digit`, at: 5},
+		{name: "control character", in: "\"a\tb\"", expErr: "control character 0x09 in string, it should be escaped", at: 2},
+		{name: "bad utf-8", in: "\"a\xffb\"", expErr: "invalid UTF-8 in string", at: 2},
+		{name: "truncated utf-8", in: "\"a\xc3\"", expErr: "invalid UTF-8 in string", at: 3},
+		{name: "overlong utf-8", in: "\"\xe0\x80\x80\"", expErr: "invalid UTF-8 in string", at: 2},
+		{name: "utf-8 surrogate", in: "\"\xed\xa0\x80\"", expErr: "invalid UTF-8 in string", at: 2},
+		{name: "missing comma", in: `{"a":1 "b":2}`, expErr: `expected ',' or '}' but found '"'`, at: 7},
+		{name: "missing colon", in: `{"a" 1}`, expErr: `expected ':' but found '1'`, at: 5},
+		{name: "key isn't a string", in: `{a:1}`, expErr: `expected an object key but found 'a'`, at: 1},
+		{name: "trailing comma in object", in: `{"a":1,}`, expErr: `expected an object key but found '}'`, at: 7},
+		{name: "trailing comma in array", in: `[1,]`, expErr: `expected a value but found ']'`, at: 3},
+		{name: "mismatched closer", in: `[1}`, expErr: `expected ',' or ']' but found '}'`, at: 2},
+		{name: "stray closer", in: `[1]]`, expErr: `expected a value but found ']'`, at: 3},
+		{name: "unclosed", in: `{"a":[1`, expErr: "unexpected end of input", at: 7},
+		{name: "unclosed string", in: `"abc`, expErr: "unexpected end of input", at: 4},
+		{name: "single quotes", in: `['a']`, expErr: `expected a value but found '\''`, at: 1},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			v := validator{}
+			_, err := v.check([]byte(tc.in))
+			if err == nil {
+				err = v.end()
+			}
+
+			if tc.expErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, ErrInvalidJSON{tc.at, tc.expErr}, err)
+		})
+	}
+}
+
+func TestStrict(t *testing.T) {
+	j := New(strings.NewReader(`[{"a":1}, {"b":2}, {"c":3 "d":4}]`))
+	j.chunkSize = 4
+	j.Strict()
+
+	it, err := j.IterArray()
+	assert.NoError(t, err)
+
+	var got []string
+	for {
+		var more bool
+		more, err = it.Next()
+		if err != nil || !more {
+			break
+		}
+
+		b := strings.Builder{}
+		_, err = j.WriteCurrentTo(&b, true)
+		if err != nil {
+			break
+		}
+		got = append(got, b.String())
+	}
+
+	assert.Equal(t, []string{`{"a":1}`, `{"b":2}`}, got, "the values before the problem")
+	assert.Equal(t, ErrInvalidJSON{26, `expected ',' or '}' but found '"'`}, err)
+}
+
+func TestStrictEOF(t *testing.T) {
+	j := New(strings.NewReader(`[1, 2`))
+	j.Strict()
+
+	_, err := j.Next()
+	assert.NoError(t, err)
+
+	_, err = j.WriteCurrentTo(io.Discard, true)
+	assert.Equal(t, ErrInvalidJSON{5, "unexpected end of input"}, err)
+}
@@ -1,8 +1,13 @@
 package options
 
 import (
+	"bytes"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"io"
+	"strconv"
+	"strings"
 )
 
 const (
@@ -10,8 +15,66 @@ const (
 	OptExpectArray   = "expect-array"
 	OptVersion       = "version"
 	OptPreserveArray = "preserve-array"
+	OptTagPath       = "tag-path"
+	OptPointer       = "pointer"
+	OptFind          = "find"
+	OptMissing       = "missing"
+	OptDuplicateKeys = "duplicate-keys"
+	OptStrict        = "strict"
+	OptNormalizeNums = "normalize-numbers"
+	OptErrorFormat   = "error-format"
+	OptOnError       = "on-error"
+	OptRejectFile    = "reject-file"
+	OptSalvage       = "salvage"
+	OptTrailing      = "trailing"
+	OptFlattenStream = "flatten-stream"
+	OptDepth         = "depth"
+	OptEntries       = "entries"
+	OptEntriesKey    = "entries-key"
+	OptUnwind        = "unwind"
+	OptUnwindParent  = "unwind-parent"
+	OptContext       = "context"
 )
 
+// what to do when a path doesn't exist, see Set.Missing
+const (
+	MissingError   = "error"
+	MissingSkip    = "skip"
+	MissingNull    = "emit-null"
+	MissingDefault = "default"
+)
+
+// which value to use when an object has the same key more than once, see
+// Set.DuplicateKeys
+const (
+	DuplicateFirst = "first"
+	DuplicateLast  = "last"
+	DuplicateError = "error"
+	DuplicateAll   = "all"
+)
+
+// how errors are reported, see Set.ErrorFormat
+const (
+	ErrorFormatText = "text"
+	ErrorFormatJSON = "json"
+)
+
+// what to do about a bad value in an array, see Set.OnError
+const (
+	OnErrorStop = "error"
+	OnErrorSkip = "skip"
+)
+
+// what to do with anything after the top level array, see Set.Trailing
+const (
+	TrailingIgnore = "ignore"
+	TrailingError  = "error"
+	TrailingStream = "stream"
+)
+
+// DepthAll is Set.Depth for unpacking nested arrays however deep they go.
+const DepthAll = -1
+
 // New create an option handler that will parse the options from command line args
 func New(args []string) (Handler, error) {
 	var h Handler
@@ -19,11 +82,112 @@ func New(args []string) (Handler, error) {
 
 	h.FlagSet = flag.NewFlagSet("json2nd", flag.ContinueOnError)
 
-	h.StringVar(
-		&o.Path,
+	h.Var(
+		(*stringList)(&o.Paths),
 		OptPath,
+		"path to get to the JSON value you want to extract, e.g key1.key2, key1[0].key2 or key1[2:5].key2. "+
+			"Can be given more than once, and can send its values to a file of their own, e.g key1=out.ndjson",
+	)
+	h.Var(
+		(*stringList)(&o.Contexts),
+		OptContext,
+		"with -path, a path to a value before what it extracts to add to each record, e.g meta=meta or meta.source=src. "+
+			"Can be given more than once",
+	)
+	h.StringVar(
+		&o.Pointer,
+		OptPointer,
 		"",
-		"path to get to the JSON value you want to extract, e.g key1.key2",
+		"alternative to -path, a JSON pointer (RFC 6901) to the value you want to extract, e.g /key1/0/key2",
+	)
+	h.StringVar(
+		&o.Find,
+		OptFind,
+		"",
+		"search the whole document for a key, extracting the value of every match, e.g id",
+	)
+	h.Func(
+		OptMissing,
+		"what to do when a path doesn't exist: error (the default), skip, emit-null or default=<json>",
+		func(s string) error {
+			var err error
+			o.Missing, o.MissingDefault, err = parseMissing(s)
+			return err
+		},
+	)
+	h.Func(
+		OptDuplicateKeys,
+		"which value a path uses when an object repeats a key: first (the default), last, error or all",
+		func(s string) error {
+			switch s {
+			case DuplicateFirst, DuplicateLast, DuplicateError, DuplicateAll:
+				o.DuplicateKeys = s
+				return nil
+			}
+			return fmt.Errorf("should be one of %s, %s, %s or %s", DuplicateFirst, DuplicateLast, DuplicateError, DuplicateAll)
+		},
+	)
+	h.Func(
+		OptErrorFormat,
+		"how to report errors on stderr: text (the default) or json, one object per error",
+		func(s string) error {
+			switch s {
+			case ErrorFormatText, ErrorFormatJSON:
+				o.ErrorFormat = s
+				if s == ErrorFormatJSON {
+					// errors are ours to report, without the usage:
+					h.SetOutput(io.Discard)
+				}
+				return nil
+			}
+			return fmt.Errorf("should be one of %s or %s", ErrorFormatText, ErrorFormatJSON)
+		},
+	)
+	h.Func(
+		OptOnError,
+		"what to do when a value in an array is bad: error (the default) or skip, which carries on from the next value",
+		func(s string) error {
+			switch s {
+			case OnErrorStop, OnErrorSkip:
+				o.OnError = s
+				return nil
+			}
+			return fmt.Errorf("should be one of %s or %s", OnErrorStop, OnErrorSkip)
+		},
+	)
+	h.StringVar(
+		&o.RejectFile,
+		OptRejectFile,
+		"",
+		"with -on-error=skip, a file to write the values we skip to, as JSON lines with their position",
+	)
+	h.BoolVar(
+		&o.Salvage,
+		OptSalvage,
+		false,
+		"if the input is cut off part way through an array, keep the values before that and exit with a partial status",
+	)
+	h.Func(
+		OptTrailing,
+		"what to do with anything after the top level array: ignore, error or stream (carry on with it as a stream of values). "+
+			"The default is error with -strict, otherwise ignore",
+		func(s string) error {
+			switch s {
+			case TrailingIgnore, TrailingError, TrailingStream:
+				o.Trailing = s
+				return nil
+			}
+			return fmt.Errorf("should be one of %s, %s or %s", TrailingIgnore, TrailingError, TrailingStream)
+		},
+	)
+	h.Func(
+		OptDepth,
+		"how many levels of nested arrays to unpack into records, e.g 2 turns [[1,2],[3]] into 1, 2 and 3, or all. The default is 1",
+		func(s string) error {
+			var err error
+			o.Depth, err = parseDepth(s)
+			return err
+		},
 	)
 	h.BoolVar(
 		&o.ExpectArray,
@@ -44,14 +208,103 @@ func New(args []string) (Handler, error) {
 		"instead of turning the top-level array into NDJSON preserve the array, useful for JSON streams",
 	)
 
+	h.BoolVar(
+		&o.FlattenStream,
+		OptFlattenStream,
+		false,
+		"treat the input as a stream of values, unpacking every array in it rather than just the first, e.g [1,2][3] gives 1, 2 and 3",
+	)
+
+	h.BoolVar(
+		&o.Entries,
+		OptEntries,
+		false,
+		`write each member of an object as a record of its own, e.g {"a":1} gives {"key":"a","value":1}`,
+	)
+	h.StringVar(
+		&o.EntriesKey,
+		OptEntriesKey,
+		"",
+		`like -entries but adding the key to each value (which must be an object) as this field, e.g with id {"a":{"x":1}} gives {"id":"a","x":1}`,
+	)
+
+	h.StringVar(
+		&o.Unwind,
+		OptUnwind,
+		"",
+		`write a record for each value in this array field of each object, with the object's other fields, e.g with items {"id":1,"items":[{"x":1},{"x":2}]} gives {"id":1,"x":1} and {"id":1,"x":2}`,
+	)
+	h.StringVar(
+		&o.UnwindParent,
+		OptUnwindParent,
+		"",
+		`with -unwind, put the object's other fields under this field rather than alongside, e.g with order {"order":{"id":1},"x":1}`,
+	)
+
+	h.BoolVar(
+		&o.Strict,
+		OptStrict,
+		false,
+		"check that the input is valid JSON as we go, and fail at the first problem",
+	)
+	h.BoolVar(
+		&o.NormalizeNumbers,
+		OptNormalizeNums,
+		false,
+		"write number exponents one way, with a lower case e and no + sign, e.g 1E+5 becomes 1e5",
+	)
+	h.BoolVar(
+		&o.TagPath,
+		OptTagPath,
+		false,
+		`wrap each record with the path it was found at, e.g {"path":"data.x","value":...}`,
+	)
+
+	// problems with the flags before -error-format are reported in its
+	// format too:
+	if errorFormatIn(args) == ErrorFormatJSON {
+		o.ErrorFormat = ErrorFormatJSON
+		h.SetOutput(io.Discard)
+	}
+
 	err := h.Parse(args)
 
-	if o.PreserveArray && o.ExpectArray {
-		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s", OptPreserveArray, OptExpectArray)
+	if o.EntriesKey != "" {
+		o.Entries = true
 	}
 
+	// (so that what we've got, e.g the error format, is there for reporting
+	// any problems below)
 	h.Options = o
 
+	if o.PreserveArray && o.FlattenStream {
+		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s", OptPreserveArray, OptFlattenStream)
+	}
+	if len(o.Contexts) > 0 && len(o.Paths) == 0 {
+		return h, fmt.Errorf("options conflict, -%s needs -%s", OptContext, OptPath)
+	}
+	if len(o.Contexts) > 0 && (o.Entries || o.Unwind != "") {
+		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s or -%s", OptContext, OptEntries, OptUnwind)
+	}
+	if o.UnwindParent != "" && o.Unwind == "" {
+		return h, fmt.Errorf("options conflict, -%s needs -%s", OptUnwindParent, OptUnwind)
+	}
+	if o.Unwind != "" && o.Entries {
+		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s", OptUnwind, OptEntries)
+	}
+	if o.PreserveArray && o.ExpectArray {
+		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s", OptPreserveArray, OptExpectArray)
+	}
+	if len(o.Paths) > 0 && o.Pointer != "" {
+		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s", OptPath, OptPointer)
+	}
+	if o.RejectFile != "" && o.OnError != OnErrorSkip {
+		return h, fmt.Errorf("options conflict, -%s needs -%s=%s", OptRejectFile, OptOnError, OnErrorSkip)
+	}
+	if o.Find != "" && (len(o.Paths) > 0 || o.Pointer != "") {
+		return h, fmt.Errorf("options conflict, -%s does not work alongside -%s or -%s", OptFind, OptPath, OptPointer)
+	}
+
 	return h, err
 }
 
@@ -64,6 +317,117 @@ type Set struct {
 	ExpectArray      bool
 	PreserveArray    bool
 	JustPrintVersion bool
-	Path             string
-	Args             []string
+	TagPath          bool
+	Strict           bool
+	NormalizeNumbers bool
+	Salvage          bool
+	FlattenStream    bool
+	Entries          bool
+	Paths            []string
+	Pointer          string
+	Find             string
+	// Contexts are the paths of values to add to each record, with the
+	// field to add them as, e.g meta=meta.
+	Contexts []string
+	// Missing is what to do when a path doesn't exist (one of the Missing*
+	// constants), blank is the same as MissingError.
+	Missing string
+	// MissingDefault is the JSON to give for MissingDefault.
+	MissingDefault string
+	// DuplicateKeys is which value a path uses when an object repeats a key
+	// (one of the Duplicate* constants), blank is the same as DuplicateFirst.
+	DuplicateKeys string
+	// ErrorFormat is how errors are reported (one of the ErrorFormat*
+	// constants), blank is the same as ErrorFormatText.
+	ErrorFormat string
+	// OnError is what to do about a bad value in an array (one of the
+	// OnError* constants), blank is the same as OnErrorStop.
+	OnError string
+	// RejectFile is where to write the values skipped by OnErrorSkip.
+	RejectFile string
+	// Trailing is what to do with anything after the top level array (one
+	// of the Trailing* constants), blank means TrailingError when Strict
+	// and TrailingIgnore otherwise.
+	Trailing string
+	// EntriesKey is the field to add the key to each value as with Entries,
+	// blank for {"key":...,"value":...} records.
+	EntriesKey string
+	// Unwind is the array field of each object to write a record for each
+	// value of, along with the object's other fields.
+	Unwind string
+	// UnwindParent is the field to put the other fields under with Unwind,
+	// blank to put them alongside.
+	UnwindParent string
+	// Depth is how many levels of nested arrays to unpack, DepthAll for all
+	// of them. 0 is the same as 1, just the array itself.
+	Depth int
+	Args  []string
+}
+
+func parseMissing(s string) (string, string, error) {
+	switch s {
+	case MissingError, MissingSkip, MissingNull:
+		return s, "", nil
+	}
+
+	if !strings.HasPrefix(s, MissingDefault+"=") {
+		return "", "", fmt.Errorf("should be one of %s, %s, %s or %s=<json>", MissingError, MissingSkip, MissingNull, MissingDefault)
+	}
+
+	def := bytes.Buffer{}
+	err := json.Compact(&def, []byte(strings.TrimPrefix(s, MissingDefault+"=")))
+	if err != nil {
+		return "", "", fmt.Errorf("bad default JSON: %w", err)
+	}
+
+	return MissingDefault, def.String(), nil
+}
+
+// errorFormatIn looks through args for -error-format ahead of parsing them,
+// giving the last value it's set to.
+func errorFormatIn(args []string) string {
+	format := ""
+	for i, a := range args {
+		if a == "--" {
+			break
+		}
+		if !strings.HasPrefix(a, "-") {
+			continue
+		}
+
+		name := strings.TrimPrefix(strings.TrimPrefix(a, "-"), "-")
+		if name == OptErrorFormat && i+1 < len(args) {
+			format = args[i+1]
+		} else if strings.HasPrefix(name, OptErrorFormat+"=") {
+			format = strings.TrimPrefix(name, OptErrorFormat+"=")
+		}
+	}
+	return format
+}
+
+func parseDepth(s string) (int, error) {
+	if s == "all" {
+		return DepthAll, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, fmt.Errorf("should be a number of levels from 1 up, or all")
+	}
+	return n, nil
+}
+
+// stringList is a flag that can be given more than once.
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, " ")
+}
+
+func (l *stringList) Set(s string) error {
+	*l = append(*l, s)
+	return nil
 }
@@ -2,6 +2,7 @@ package options
 
 import (
 	"flag"
+	"io"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -37,7 +38,317 @@ func TestOptParse(t *testing.T) {
 			name: "path",
 			in:   []string{"-path", "xyz.boo"},
 			exp: Set{
-				Path: "xyz.boo",
+				Paths: []string{"xyz.boo"},
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "multiple paths",
+			in:   []string{"-path", "users=users.ndjson", "-path", "groups"},
+			exp: Set{
+				Paths: []string{"users=users.ndjson", "groups"},
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "tag path",
+			in:   []string{"-path", "x.*", "-tag-path"},
+			exp: Set{
+				Paths:   []string{"x.*"},
+				TagPath: true,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "pointer",
+			in:   []string{"-pointer", "/xyz/boo"},
+			exp: Set{
+				Pointer: "/xyz/boo",
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "path + pointer",
+			in:   []string{"-path", "xyz", "-pointer", "/xyz"},
+			exp:  Set{Paths: []string{"xyz"}, Pointer: "/xyz"},
+			checkErr: func(t *testing.T, e error) {
+				if assert.Error(t, e) {
+					assert.Contains(t, e.Error(), "options conflict", "error message")
+				}
+			},
+		},
+		{
+			name: "find",
+			in:   []string{"-find", "id"},
+			exp: Set{
+				Find: "id",
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "find + path",
+			in:   []string{"-path", "xyz", "-find", "id"},
+			exp:  Set{Paths: []string{"xyz"}, Find: "id"},
+			checkErr: func(t *testing.T, e error) {
+				if assert.Error(t, e) {
+					assert.Contains(t, e.Error(), "options conflict", "error message")
+				}
+			},
+		},
+		{
+			name: "missing",
+			in:   []string{"-missing", "skip"},
+			exp: Set{
+				Missing: MissingSkip,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "missing with a default",
+			in:   []string{"-missing", `default={"a": [1, 2]}`},
+			exp: Set{
+				Missing:        MissingDefault,
+				MissingDefault: `{"a":[1,2]}`,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "missing with a bad default",
+			in:   []string{"-missing", `default={`},
+			checkErr: func(t *testing.T, e error) {
+				if assert.Error(t, e) {
+					assert.Contains(t, e.Error(), "bad default JSON")
+				}
+			},
+		},
+		{
+			name: "missing with a bad policy",
+			in:   []string{"-missing", `ignore`},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "duplicate keys",
+			in:   []string{"-duplicate-keys", "last"},
+			exp: Set{
+				DuplicateKeys: DuplicateLast,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "duplicate keys with a bad policy",
+			in:   []string{"-duplicate-keys", "middle"},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "error format",
+			in:   []string{"-error-format", "json"},
+			exp: Set{
+				ErrorFormat: ErrorFormatJSON,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "bad error format",
+			in:   []string{"-error-format", "xml"},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "skip bad values",
+			in:   []string{"-on-error", "skip", "-reject-file", "rejects.jsonl"},
+			exp: Set{
+				OnError:    OnErrorSkip,
+				RejectFile: "rejects.jsonl",
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "reject file without skipping",
+			in:   []string{"-reject-file", "rejects.jsonl"},
+			exp:  Set{RejectFile: "rejects.jsonl"},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "salvage",
+			in:   []string{"-salvage"},
+			exp: Set{
+				Salvage: true,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "trailing",
+			in:   []string{"-trailing", "stream"},
+			exp: Set{
+				Trailing: TrailingStream,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "bad trailing",
+			in:   []string{"-trailing", "more"},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "flatten stream",
+			in:   []string{"-flatten-stream"},
+			exp: Set{
+				FlattenStream: true,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "flatten stream and preserve array",
+			in:   []string{"-flatten-stream", "-preserve-array"},
+			exp:  Set{FlattenStream: true, PreserveArray: true},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "depth",
+			in:   []string{"-depth", "3"},
+			exp: Set{
+				Depth: 3,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "depth all",
+			in:   []string{"-depth", "all"},
+			exp: Set{
+				Depth: DepthAll,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "bad depth",
+			in:   []string{"-depth", "0"},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "entries",
+			in:   []string{"-entries"},
+			exp: Set{
+				Entries: true,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "entries key",
+			in:   []string{"-entries-key", "id"},
+			exp: Set{
+				Entries:    true,
+				EntriesKey: "id",
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "unwind",
+			in:   []string{"-unwind", "items", "-unwind-parent", "order"},
+			exp: Set{
+				Unwind:       "items",
+				UnwindParent: "order",
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "unwind parent without unwind",
+			in:   []string{"-unwind-parent", "order"},
+			exp:  Set{UnwindParent: "order"},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "unwind and entries",
+			in:   []string{"-unwind", "items", "-entries"},
+			exp:  Set{Unwind: "items", Entries: true},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "context",
+			in:   []string{"-path", "data.items", "-context", "meta=meta", "-context", "meta.ts=ts"},
+			exp: Set{
+				Paths:    []string{"data.items"},
+				Contexts: []string{"meta=meta", "meta.ts=ts"},
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "context without a path",
+			in:   []string{"-context", "meta"},
+			exp:  Set{Contexts: []string{"meta"}},
+			checkErr: func(t *testing.T, e error) {
+				assert.Error(t, e)
+			},
+		},
+		{
+			name: "normalize numbers",
+			in:   []string{"-normalize-numbers"},
+			exp: Set{
+				NormalizeNumbers: true,
+			},
+			checkErr: func(t *testing.T, e error) {
+				assert.NoError(t, e)
+			},
+		},
+		{
+			name: "strict",
+			in:   []string{"-strict"},
+			exp: Set{
+				Strict: true,
 			},
 			checkErr: func(t *testing.T, e error) {
 				assert.NoError(t, e)
@@ -73,7 +384,7 @@ func TestOptParse(t *testing.T) {
 		{
 			name: "preserve array + expect array",
 			in:   []string{"-preserve-array", "-expect-array"},
-			exp:  Set{},
+			exp:  Set{PreserveArray: true, ExpectArray: true},
 			checkErr: func(t *testing.T, e error) {
 				is := assert.Error(t, e)
 				if !is {
@@ -102,4 +413,28 @@ func TestOptParse(t *testing.T) {
 	}
 }
 
+func TestErrorFormatJSONQuietensFlags(t *testing.T) {
+	h, err := New([]string{"-error-format", "json", "-size"})
+	assert.Error(t, err)
+	assert.Equal(t, io.Discard, h.Output(), "no usage with the JSON error")
+
+	h, err = New([]string{"-size"})
+	assert.Error(t, err)
+	assert.NotEqual(t, io.Discard, h.Output(), "usage as normal")
+
+	for _, args := range [][]string{
+		{"-depth", "x", "-error-format=json"},
+		{"-depth", "x", "--error-format", "json"},
+	} {
+		h, err = New(args)
+		assert.Error(t, err, args)
+		assert.Equal(t, ErrorFormatJSON, h.Options.ErrorFormat, args)
+		assert.Equal(t, io.Discard, h.Output(), "no usage with the bad flag first: %v", args)
+	}
+
+	h, err = New([]string{"-depth", "x", "--", "-error-format=json"})
+	assert.Error(t, err)
+	assert.Equal(t, "", h.Options.ErrorFormat, "not a flag after --")
+}
+
 //TODO method to get the option description we need for error messages
@@ -0,0 +1,333 @@
+// Package path parses the expressions used to pick out the JSON value we want
+// to unpack, e.g: pages[0].items
+package path
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+type Kind int
+
+const (
+	// Key looks up a key in an object.
+	Key Kind = iota
+	// Index picks one value out of an array.
+	Index
+	// Slice picks a range of values out of an array.
+	Slice
+	// Wildcard picks every member of an object or value of an array.
+	Wildcard
+	// Token is a JSON Pointer reference token, it looks up Key in an object
+	// or Index in an array (where Index is -1 if the token can't be one).
+	Token
+	// Descendant looks up Key in every object at any depth below, like
+	// JSONPath's ..key
+	Descendant
+)
+
+// Step is one node of a path.
+type Step struct {
+	Kind Kind
+	Key  string
+	// Index is the array index for an Index step, or the start of a Slice.
+	Index int
+	// End of a Slice (exclusive), -1 when the slice runs to the end of the array.
+	End int
+}
+
+type Path []Step
+
+var (
+	ErrBlankNode         = errors.New("blank path node, did you have a double dot?")
+	ErrExpectedSeparator = errors.New("expected '.' or '['")
+	ErrUnclosedQuote     = errors.New("quoted key is never closed")
+	ErrUnexpectedQuote   = errors.New("quotes must go around the whole key")
+	ErrTrailingEscape    = errors.New("nothing to escape at the end of the path")
+	ErrUnclosedIndex     = errors.New("array index is never closed")
+	ErrBadIndex          = errors.New("array index isn't a number")
+	ErrNegativeIndex     = errors.New("negative array indexes are not supported")
+	ErrBlankTarget       = errors.New("blank name after =")
+)
+
+// SyntaxError is what we get for a path we can't parse, Column is where (in
+// characters, from 1) we found the problem.
+type SyntaxError struct {
+	Path   string
+	Column int
+	Err    error
+}
+
+func (e SyntaxError) Error() string {
+	return fmt.Sprintf("bad path (%s) at column %d: %v", e.Path, e.Column, e.Err)
+}
+
+func (e SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// syntaxErrorAt gives us a SyntaxError for a problem at byte i of s.
+func syntaxErrorAt(s string, i int, err error) error {
+	return SyntaxError{
+		Path:   s,
+		Column: utf8.RuneCountInString(s[:i]) + 1,
+		Err:    err,
+	}
+}
+
+// Parse a dotted path, keys are separated by dots and may be followed by
+// array indexes ([1]) or slices ([1:5], [2:], [:3]). A * in place of a key or
+// index matches everything at that level.
+//
+// Keys containing awkward characters can be wrapped in double quotes
+// (a."b.c".d), or have those characters escaped with a backslash (a.b\.c.d).
+func Parse(s string) (Path, error) {
+	ps := parser{s: s}
+	return ps.parse()
+}
+
+// ParseTarget parses a dotted path which may be followed by =name, to say
+// where the values it leads to should go, e.g: users=users.ndjson
+// An = within a key then needs escaping or quoting.
+func ParseTarget(s string) (Path, string, error) {
+	ps := parser{s: s, target: true}
+
+	p, err := ps.parse()
+	if err != nil {
+		return nil, "", err
+	}
+
+	if ps.i == len(s) {
+		return p, "", nil
+	}
+
+	name := s[ps.i+1:]
+	if name == "" {
+		return nil, "", ps.errAt(ps.i+1, ErrBlankTarget)
+	}
+
+	return p, name, nil
+}
+
+type parser struct {
+	s string
+	i int
+	// target is set if the path may be followed by =name
+	target bool
+}
+
+func (ps *parser) parse() (Path, error) {
+	var p Path
+
+	for {
+		// we expect a key unless the path starts with an index:
+		if !(ps.i == 0 && strings.HasPrefix(ps.s, "[")) {
+			step, err := ps.key()
+			if err != nil {
+				return nil, err
+			}
+
+			p = append(p, step)
+		}
+
+		for ps.i < len(ps.s) && ps.s[ps.i] == '[' {
+			step, err := ps.brackets()
+			if err != nil {
+				return nil, err
+			}
+
+			p = append(p, step)
+		}
+
+		if ps.i == len(ps.s) || (ps.target && ps.s[ps.i] == '=') {
+			return p, nil
+		}
+
+		if ps.s[ps.i] != '.' {
+			return nil, ps.errAt(ps.i, ErrExpectedSeparator)
+		}
+		ps.i++
+	}
+}
+
+func (ps *parser) errAt(i int, err error) error {
+	return syntaxErrorAt(ps.s, i, err)
+}
+
+func (ps *parser) key() (Step, error) {
+	start := ps.i
+
+	if start < len(ps.s) && ps.s[start] == '"' {
+		key, err := ps.quoted()
+		if err != nil {
+			return Step{}, err
+		}
+		return Step{Kind: Key, Key: key}, nil
+	}
+
+	b := strings.Builder{}
+	escaped := false
+
+	for ; ps.i < len(ps.s); ps.i++ {
+		c := ps.s[ps.i]
+		if escaped {
+			escaped = false
+		} else if c == '\\' {
+			escaped = true
+			continue
+		} else if c == '.' || c == '[' || (ps.target && c == '=') {
+			break
+		} else if c == '"' {
+			return Step{}, ps.errAt(ps.i, ErrUnexpectedQuote)
+		}
+		b.WriteByte(c)
+	}
+
+	if escaped {
+		return Step{}, ps.errAt(ps.i-1, ErrTrailingEscape)
+	}
+	if ps.i == start {
+		return Step{}, ps.errAt(start, ErrBlankNode)
+	}
+
+	// an escaped \* is just a key:
+	if ps.s[start:ps.i] == "*" {
+		return Step{Kind: Wildcard}, nil
+	}
+
+	return Step{Kind: Key, Key: b.String()}, nil
+}
+
+// quoted reads a key wrapped in double quotes, within which a backslash
+// escapes the next character.
+func (ps *parser) quoted() (string, error) {
+	start := ps.i
+	b := strings.Builder{}
+
+	for ps.i++; ps.i < len(ps.s); ps.i++ {
+		c := ps.s[ps.i]
+		if c == '\\' && ps.i+1 < len(ps.s) {
+			ps.i++
+			c = ps.s[ps.i]
+		} else if c == '"' {
+			ps.i++
+			return b.String(), nil
+		}
+		b.WriteByte(c)
+	}
+
+	return "", ps.errAt(start, ErrUnclosedQuote)
+}
+
+// brackets parses an index or slice.
+func (ps *parser) brackets() (Step, error) {
+	start := ps.i
+	end := strings.IndexByte(ps.s[start:], ']')
+	if end < 0 {
+		return Step{}, ps.errAt(start, ErrUnclosedIndex)
+	}
+	end += start
+	ps.i = end + 1
+
+	from, to, isSlice := ps.s[start+1:end], "", false
+	if from == "*" {
+		return Step{Kind: Wildcard}, nil
+	}
+
+	colon := strings.IndexByte(from, ':')
+	if colon >= 0 {
+		from, to, isSlice = from[:colon], from[colon+1:], true
+	}
+
+	first, err := ps.index(from, start+1, 0, isSlice)
+	if err != nil {
+		return Step{}, err
+	}
+
+	if !isSlice {
+		return Step{Kind: Index, Index: first}, nil
+	}
+
+	stop, err := ps.index(to, start+colon+2, -1, true)
+	if err != nil {
+		return Step{}, err
+	}
+
+	return Step{Kind: Slice, Index: first, End: stop}, nil
+}
+
+// index parses the number s, found at position at in the path.
+func (ps *parser) index(s string, at int, def int, optional bool) (int, error) {
+	if s == "" && optional {
+		return def, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, ps.errAt(at, ErrBadIndex)
+	}
+	if n < 0 {
+		return 0, ps.errAt(at, ErrNegativeIndex)
+	}
+
+	return n, nil
+}
+
+func (s Step) String() string {
+	switch s.Kind {
+	case Index:
+		return fmt.Sprintf("[%d]", s.Index)
+	case Slice:
+		b := strings.Builder{}
+		b.WriteByte('[')
+		if s.Index > 0 {
+			b.WriteString(strconv.Itoa(s.Index))
+		}
+		b.WriteByte(':')
+		if s.End >= 0 {
+			b.WriteString(strconv.Itoa(s.End))
+		}
+		b.WriteByte(']')
+		return b.String()
+	case Wildcard:
+		return "*"
+	case Descendant:
+		return ".." + quoteKey(s.Key)
+	case Token:
+		return s.Key
+	}
+
+	return quoteKey(s.Key)
+}
+
+// quoteKey quotes a key if it needs to be, to be read back in to a path.
+func quoteKey(k string) string {
+	if k != "" && k != "*" && !strings.ContainsAny(k, `.[]"\=`) {
+		return k
+	}
+
+	b := strings.Builder{}
+	b.WriteByte('"')
+	for i := 0; i < len(k); i++ {
+		if k[i] == '"' || k[i] == '\\' {
+			b.WriteByte('\\')
+		}
+		b.WriteByte(k[i])
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func (p Path) String() string {
+	b := strings.Builder{}
+	for i, s := range p {
+		if i > 0 && (s.Kind == Key || s.Kind == Wildcard) {
+			b.WriteByte('.')
+		}
+		b.WriteString(s.String())
+	}
+	return b.String()
+}
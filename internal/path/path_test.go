@@ -0,0 +1,252 @@
+package path
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+
+	cases := []struct {
+		name   string
+		in     string
+		exp    Path
+		expErr error
+	}{
+		{
+			name: "single key",
+			in:   "x",
+			exp:  Path{{Kind: Key, Key: "x"}},
+		},
+		{
+			name: "dotted keys",
+			in:   "x.y",
+			exp:  Path{{Kind: Key, Key: "x"}, {Kind: Key, Key: "y"}},
+		},
+		{
+			name: "index",
+			in:   "pages[0].items",
+			exp: Path{
+				{Kind: Key, Key: "pages"},
+				{Kind: Index, Index: 0},
+				{Kind: Key, Key: "items"},
+			},
+		},
+		{
+			name: "leading index",
+			in:   "[3]",
+			exp:  Path{{Kind: Index, Index: 3}},
+		},
+		{
+			name: "index of an index",
+			in:   "x[1][2]",
+			exp: Path{
+				{Kind: Key, Key: "x"},
+				{Kind: Index, Index: 1},
+				{Kind: Index, Index: 2},
+			},
+		},
+		{
+			name: "slice",
+			in:   "pages[2:5].items",
+			exp: Path{
+				{Kind: Key, Key: "pages"},
+				{Kind: Slice, Index: 2, End: 5},
+				{Kind: Key, Key: "items"},
+			},
+		},
+		{
+			name: "open slices",
+			in:   "x[2:][:3][:]",
+			exp: Path{
+				{Kind: Key, Key: "x"},
+				{Kind: Slice, Index: 2, End: -1},
+				{Kind: Slice, Index: 0, End: 3},
+				{Kind: Slice, Index: 0, End: -1},
+			},
+		},
+		{
+			name: "wildcards",
+			in:   "data.*.records[*]",
+			exp: Path{
+				{Kind: Key, Key: "data"},
+				{Kind: Wildcard},
+				{Kind: Key, Key: "records"},
+				{Kind: Wildcard},
+			},
+		},
+		{
+			name: "quoted keys",
+			in:   `meta."k8s.io/name".items."".x`,
+			exp: Path{
+				{Kind: Key, Key: "meta"},
+				{Kind: Key, Key: "k8s.io/name"},
+				{Kind: Key, Key: "items"},
+				{Kind: Key, Key: ""},
+				{Kind: Key, Key: "x"},
+			},
+		},
+		{
+			name: "quoted keys with escapes and indexes",
+			in:   `"a\"b\\"[1]."*"`,
+			exp: Path{
+				{Kind: Key, Key: `a"b\`},
+				{Kind: Index, Index: 1},
+				{Kind: Key, Key: "*"},
+			},
+		},
+		{
+			name: "escaped characters",
+			in:   `a\.b.c\[0\]\ d.\*`,
+			exp: Path{
+				{Kind: Key, Key: "a.b"},
+				{Kind: Key, Key: "c[0] d"},
+				{Kind: Key, Key: "*"},
+			},
+		},
+		{
+			name:   "blank node",
+			in:     "x..y",
+			expErr: SyntaxError{"x..y", 3, ErrBlankNode},
+		},
+		{
+			name:   "leading dot",
+			in:     ".x",
+			expErr: SyntaxError{".x", 1, ErrBlankNode},
+		},
+		{
+			name:   "trailing dot",
+			in:     "x.",
+			expErr: SyntaxError{"x.", 3, ErrBlankNode},
+		},
+		{
+			name:   "column counts characters",
+			in:     "ü..y",
+			expErr: SyntaxError{"ü..y", 3, ErrBlankNode},
+		},
+		{
+			name:   "unclosed index",
+			in:     "x[1",
+			expErr: SyntaxError{"x[1", 2, ErrUnclosedIndex},
+		},
+		{
+			name:   "index isn't a number",
+			in:     "x[y]",
+			expErr: SyntaxError{"x[y]", 3, ErrBadIndex},
+		},
+		{
+			name:   "slice end isn't a number",
+			in:     "x[1:y]",
+			expErr: SyntaxError{"x[1:y]", 5, ErrBadIndex},
+		},
+		{
+			name:   "negative index",
+			in:     "x[-1]",
+			expErr: SyntaxError{"x[-1]", 3, ErrNegativeIndex},
+		},
+		{
+			name:   "garbage after index",
+			in:     "x[1]y",
+			expErr: SyntaxError{"x[1]y", 5, ErrExpectedSeparator},
+		},
+		{
+			name:   "garbage after quotes",
+			in:     `"x"y`,
+			expErr: SyntaxError{`"x"y`, 4, ErrExpectedSeparator},
+		},
+		{
+			name:   "unclosed quote",
+			in:     `a."x.y`,
+			expErr: SyntaxError{`a."x.y`, 3, ErrUnclosedQuote},
+		},
+		{
+			name:   "quote part way through a key",
+			in:     `a.x"y"`,
+			expErr: SyntaxError{`a.x"y"`, 4, ErrUnexpectedQuote},
+		},
+		{
+			name:   "trailing escape",
+			in:     `a.x\`,
+			expErr: SyntaxError{`a.x\`, 4, ErrTrailingEscape},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			get, err := Parse(tc.in)
+			assert.Equal(t, tc.exp, get, "path")
+			assert.Equal(t, tc.expErr, err, "error")
+		})
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, in := range []string{"x", "x.y", "pages[0].items", "[3]", "x[2:5][:3][1:][:]", "*.x.*", `a."b.c"."\"*\"".""."*"`} {
+		t.Run(in, func(t *testing.T) {
+			p, err := Parse(in)
+			assert.NoError(t, err)
+			assert.Equal(t, in, p.String())
+		})
+	}
+}
+
+func TestSyntaxError(t *testing.T) {
+	_, err := Parse("x..y")
+	assert.ErrorIs(t, err, ErrBlankNode)
+	assert.EqualError(t, err, "bad path (x..y) at column 3: blank path node, did you have a double dot?")
+}
+
+func TestParseTarget(t *testing.T) {
+
+	cases := []struct {
+		name    string
+		in      string
+		exp     Path
+		expName string
+		expErr  error
+	}{
+		{
+			name: "no target",
+			in:   "a.b",
+			exp:  Path{{Kind: Key, Key: "a"}, {Kind: Key, Key: "b"}},
+		},
+		{
+			name:    "target",
+			in:      "users=users.ndjson",
+			exp:     Path{{Kind: Key, Key: "users"}},
+			expName: "users.ndjson",
+		},
+		{
+			name:    "target after an index",
+			in:      "a[1]=x=y",
+			exp:     Path{{Kind: Key, Key: "a"}, {Kind: Index, Index: 1}},
+			expName: "x=y",
+		},
+		{
+			name:    "quoted and escaped =",
+			in:      `"a=b".c\=d=out`,
+			exp:     Path{{Kind: Key, Key: "a=b"}, {Kind: Key, Key: "c=d"}},
+			expName: "out",
+		},
+		{
+			name:   "blank target",
+			in:     "a=",
+			expErr: SyntaxError{"a=", 3, ErrBlankTarget},
+		},
+		{
+			name:   "blank path",
+			in:     "=out",
+			expErr: SyntaxError{"=out", 1, ErrBlankNode},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			get, name, err := ParseTarget(tc.in)
+			assert.Equal(t, tc.exp, get, "path")
+			assert.Equal(t, tc.expName, name, "name")
+			assert.Equal(t, tc.expErr, err, "error")
+		})
+	}
+}
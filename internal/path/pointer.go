@@ -0,0 +1,96 @@
+package path
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrPointerStart  = errors.New("JSON pointers should start with a '/'")
+	ErrPointerEscape = errors.New("~ should be followed by 0 or 1")
+)
+
+// ParsePointer parses an RFC 6901 JSON Pointer, e.g: /app.version/items/0
+func ParsePointer(s string) (Path, error) {
+	if s == "" {
+		// the whole document
+		return Path{}, nil
+	}
+
+	if s[0] != '/' {
+		return nil, syntaxErrorAt(s, 0, ErrPointerStart)
+	}
+
+	var p Path
+	b := strings.Builder{}
+
+	for i := 1; ; i++ {
+		if i == len(s) || s[i] == '/' {
+			key := b.String()
+			p = append(p, Step{Kind: Token, Key: key, Index: tokenIndex(key)})
+			b.Reset()
+
+			if i == len(s) {
+				return p, nil
+			}
+			continue
+		}
+
+		c := s[i]
+		if c == '~' {
+			if i+1 == len(s) || (s[i+1] != '0' && s[i+1] != '1') {
+				return nil, syntaxErrorAt(s, i, ErrPointerEscape)
+			}
+
+			i++
+			c = '~'
+			if s[i] == '1' {
+				c = '/'
+			}
+		}
+		b.WriteByte(c)
+	}
+}
+
+// tokenIndex gives us the array index a token refers to, or -1 if it can't
+// refer to one.
+func tokenIndex(token string) int {
+	if token == "" || (token[0] == '0' && len(token) > 1) {
+		return -1
+	}
+
+	for i := 0; i < len(token); i++ {
+		if token[i] < '0' || token[i] > '9' {
+			return -1
+		}
+	}
+
+	n, err := strconv.Atoi(token)
+	if err != nil {
+		return -1
+	}
+
+	return n
+}
+
+// Pointer formats the path as a JSON Pointer. Only Key and Index steps (and
+// Tokens) have a pointer form, anything else is written as it would be in a
+// dotted path.
+func (p Path) Pointer() string {
+	b := strings.Builder{}
+	for _, s := range p {
+		b.WriteByte('/')
+		switch s.Kind {
+		case Key, Token:
+			b.WriteString(pointerEscaper.Replace(s.Key))
+		case Index:
+			b.WriteString(strconv.Itoa(s.Index))
+		default:
+			b.WriteString(s.String())
+		}
+	}
+	return b.String()
+}
+
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
@@ -0,0 +1,88 @@
+package path
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePointer(t *testing.T) {
+
+	cases := []struct {
+		name   string
+		in     string
+		exp    Path
+		expErr error
+	}{
+		{
+			name: "whole document",
+			in:   "",
+			exp:  Path{},
+		},
+		{
+			name: "blank key",
+			in:   "/",
+			exp:  Path{{Kind: Token, Key: "", Index: -1}},
+		},
+		{
+			name: "keys with dots",
+			in:   "/app.version/items",
+			exp: Path{
+				{Kind: Token, Key: "app.version", Index: -1},
+				{Kind: Token, Key: "items", Index: -1},
+			},
+		},
+		{
+			name: "indexes",
+			in:   "/0/10/01/-",
+			exp: Path{
+				{Kind: Token, Key: "0", Index: 0},
+				{Kind: Token, Key: "10", Index: 10},
+				{Kind: Token, Key: "01", Index: -1},
+				{Kind: Token, Key: "-", Index: -1},
+			},
+		},
+		{
+			name: "escapes",
+			in:   "/a~1b/m~0n/~01",
+			exp: Path{
+				{Kind: Token, Key: "a/b", Index: -1},
+				{Kind: Token, Key: "m~n", Index: -1},
+				{Kind: Token, Key: "~1", Index: -1},
+			},
+		},
+		{
+			name:   "no leading slash",
+			in:     "a/b",
+			expErr: SyntaxError{"a/b", 1, ErrPointerStart},
+		},
+		{
+			name:   "bad escape",
+			in:     "/a~2",
+			expErr: SyntaxError{"/a~2", 3, ErrPointerEscape},
+		},
+		{
+			name:   "trailing tilde",
+			in:     "/a~",
+			expErr: SyntaxError{"/a~", 3, ErrPointerEscape},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			get, err := ParsePointer(tc.in)
+			assert.Equal(t, tc.exp, get, "path")
+			assert.Equal(t, tc.expErr, err, "error")
+		})
+	}
+}
+
+func TestPointer(t *testing.T) {
+	for _, in := range []string{"", "/", "/app.version/items", "/a~1b/m~0n/0"} {
+		t.Run(in, func(t *testing.T) {
+			p, err := ParsePointer(in)
+			assert.NoError(t, err)
+			assert.Equal(t, in, p.Pointer())
+		})
+	}
+}
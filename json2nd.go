@@ -16,9 +16,14 @@ func main() {
 	oh, err := options.New(os.Args[1:])
 	if err != nil {
 		if err == flag.ErrHelp {
-			os.Exit(0)
+			// -error-format=json quietens the flags, but help was asked for:
+			if oh.Options.ErrorFormat == options.ErrorFormatJSON {
+				oh.SetOutput(os.Stderr)
+				oh.PrintDefaults()
+			}
+			os.Exit(exitOK)
 		}
-		bail(err)
+		bail(optionError{err}, oh.Options.ErrorFormat)
 	}
 
 	args := oh.Args()
@@ -28,27 +33,48 @@ func main() {
 		justPrintVersion()
 	}
 
+	outputs, closeOutputs, err := openOutputs(opts.Paths)
+	bailIfError(err, opts.ErrorFormat)
+
+	rejects, closeRejects, err := openRejects(opts.RejectFile)
+	bailIfError(err, opts.ErrorFormat)
+
+	p := processor{
+		in:       os.Stdin,
+		out:      os.Stdout,
+		options:  opts,
+		buffered: true,
+		outputs:  outputs,
+		rejects:  rejects,
+		warn:     os.Stderr,
+	}
+
 	if len(args) > 0 {
-		err := filemode(args, os.Stdout, opts)
-		bailIfError(err)
-		return
+		err = filemode(args, p)
+	} else {
+		err = p.run()
 	}
 
-	err = processor{os.Stdin, os.Stdout, opts, true}.run()
-	bailIfError(err)
+	closeErr := closeOutputs()
+	closeRejectsErr := closeRejects()
+	bailIfError(err, opts.ErrorFormat)
+	bailIfError(closeErr, opts.ErrorFormat)
+	bailIfError(closeRejectsErr, opts.ErrorFormat)
 }
 
-func bailIfError(e error) {
+func bailIfError(e error, format string) {
 	if e == nil {
 		return
 	}
 
-	bail(e)
+	bail(e, format)
 }
 
-func bail(e error) {
-	fmt.Fprintln(os.Stderr, e)
-	os.Exit(1)
+func bail(e error, format string) {
+	report(os.Stderr, format, "", e)
+
+	_, code := errorKind(e)
+	os.Exit(code)
 }
 
 func justPrintVersion() {
@@ -62,5 +88,5 @@ func justPrintVersion() {
 			fmt.Println("don't know")
 		}
 	}
-	os.Exit(0)
+	os.Exit(exitOK)
 }
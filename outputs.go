@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/draxil/json2nd/internal/path"
+)
+
+// openOutputs creates the files named by -path options (e.g x=x.ndjson),
+// giving them back by name along with a function to flush and close them.
+func openOutputs(paths []string) (map[string]io.Writer, func() error, error) {
+	outputs := map[string]io.Writer{}
+	var files []*os.File
+	var buffers []*bufio.Writer
+
+	closeAll := func() error {
+		var first error
+		for i, f := range files {
+			err := buffers[i].Flush()
+			if err == nil {
+				err = f.Close()
+			} else {
+				f.Close()
+			}
+			if err != nil && first == nil {
+				first = outputCloseErr(f.Name(), err)
+			}
+		}
+		return first
+	}
+
+	for _, spec := range paths {
+		_, name, err := path.ParseTarget(spec)
+		if err != nil {
+			closeAll()
+			return nil, nil, err
+		}
+		if name == "" || outputs[name] != nil {
+			continue
+		}
+
+		f, err := os.Create(name)
+		if err != nil {
+			closeAll()
+			return nil, nil, outputOpenErr(name, err)
+		}
+
+		bw := bufio.NewWriter(f)
+		files = append(files, f)
+		buffers = append(buffers, bw)
+		outputs[name] = bw
+	}
+
+	return outputs, closeAll, nil
+}
+
+// openRejects creates the -reject-file, if there is one.
+func openRejects(name string) (io.Writer, func() error, error) {
+	if name == "" {
+		return nil, func() error { return nil }, nil
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return nil, nil, outputOpenErr(name, err)
+	}
+
+	bw := bufio.NewWriter(f)
+	closeRejects := func() error {
+		err := bw.Flush()
+		if err == nil {
+			err = f.Close()
+		} else {
+			f.Close()
+		}
+		if err != nil {
+			return outputCloseErr(name, err)
+		}
+		return nil
+	}
+
+	return bw, closeRejects, nil
+}
+
+func outputOpenErr(file string, e error) error {
+	return fmt.Errorf("could not create %s: %w", file, e)
+}
+
+func outputCloseErr(file string, e error) error {
+	return fmt.Errorf("could not write %s: %w", file, e)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/draxil/json2nd/internal/options"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputs(t *testing.T) {
+	dir := t.TempDir()
+	users := filepath.Join(dir, "users.ndjson")
+	groups := filepath.Join(dir, "groups.ndjson")
+
+	paths := []string{"users=" + users, "groups=" + groups, "other"}
+	outputs, closeOutputs, err := openOutputs(paths)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Len(t, outputs, 2)
+
+	out := bytes.NewBuffer(nil)
+	opts := options.Set{Paths: paths}
+	err = filemode([]string{"./testdata/paths.json"}, processor{out: out, options: opts, outputs: outputs})
+	assert.NoError(t, err)
+	assert.NoError(t, closeOutputs())
+
+	assert.Equal(t, "[3]\n", out.String(), "stdout")
+
+	get, err := os.ReadFile(users)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"a"}`+"\n"+`{"name":"b"}`+"\n", string(get), "users")
+
+	get, err = os.ReadFile(groups)
+	assert.NoError(t, err)
+	assert.Equal(t, `{"name":"g"}`+"\n", string(get), "groups")
+}
+
+func TestOutputsBadPath(t *testing.T) {
+	_, _, err := openOutputs([]string{"x..y=out"})
+	assert.Error(t, err)
+}
+
+func TestOutputsCantCreate(t *testing.T) {
+	_, _, err := openOutputs([]string{"x=" + filepath.Join(t.TempDir(), "nope", "out")})
+	if assert.Error(t, err) {
+		assert.Contains(t, err.Error(), "could not create")
+	}
+}
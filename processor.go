@@ -2,12 +2,16 @@ package main
 
 import (
 	"bufio"
+	"bytes"
+	stdjson "encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"strings"
+	"unicode/utf8"
 
 	"github.com/draxil/json2nd/internal/json"
 	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
 )
 
 type processor struct {
@@ -15,6 +19,22 @@ type processor struct {
 	out      io.Writer
 	options  options.Set
 	buffered bool
+	// name of the input, blank for stdin
+	name string
+	// warn is where we report things we've skipped over
+	warn io.Writer
+	// rejects is where values we skip with -on-error=skip go
+	rejects io.Writer
+	// outputs are where the -path options that name an output go
+	outputs map[string]io.Writer
+	// at is where the path we're following has got to so far
+	at path.Path
+	// tag records with the path they were found at
+	tag bool
+	// contexts we add to records, see -context
+	contexts *contexts
+	// context is the one we're looking for, if we are
+	context *contextValue
 }
 
 // TODO: detect where not an object more tidily in path mode
@@ -31,51 +51,186 @@ func (p processor) run() error {
 	}
 
 	js := json.New(p.in)
-	if p.options.Path != "" {
-		return p.handlePath(js)
+	if p.options.Strict {
+		js.Strict()
 	}
 
+	if len(p.options.Paths) > 0 || p.options.Pointer != "" || p.options.Find != "" {
+		if len(p.options.Contexts) > 0 {
+			var err error
+			p.contexts, err = newContexts(p.options.Contexts)
+			if err != nil {
+				return err
+			}
+		}
+
+		branches, err := p.branches()
+		if err != nil {
+			return err
+		}
+		return js.Locate(json.Classify(p.handlePath(branches, js), p.where()))
+	}
+
+	return js.Locate(json.Classify(p.handleTop(js), p.where()))
+}
+
+func (p processor) handleTop(js *json.JSON) error {
 	c, err := js.Next()
 	if err != nil && err != io.EOF {
 		return peekErr(err)
 	}
 	if err == io.EOF {
-		return errNoJSON()
+		return errNoJSON(p.where())
+	}
+
+	if p.options.FlattenStream {
+		return p.handleStream(js)
 	}
 
 	if c != '[' || p.options.PreserveArray {
 		return p.handleNonArray(js, c, true)
 	}
 
-	return p.handleArray(js)
+	err = p.handleArray(js)
+	if err != nil {
+		return err
+	}
+	return p.handleTrailing(js)
 }
 
-func (p processor) handlePath(scan *json.JSON) error {
-	nodes := strings.Split(p.options.Path, ".")
-	return p.handlePathNodes(nodes, scan)
+// handleTrailing deals with anything after the top level array, see
+// -trailing.
+func (p processor) handleTrailing(js *json.JSON) error {
+	switch p.trailing() {
+	case options.TrailingIgnore:
+		return nil
+	case options.TrailingError:
+		c, err := js.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return errTrailing(c, p.where())
+	}
+
+	return p.handleStream(js)
 }
 
-func (p processor) handlePathNodes(nodes []string, scan *json.JSON) error {
+// handleStream unpacks each array in a stream of values, and writes out any
+// other values as they are.
+func (p processor) handleStream(js *json.JSON) error {
+	out, finishOut := p.prepOut()
+
+	for {
+		c, err := js.Next()
+		if err == io.EOF {
+			return finishOut()
+		}
+		if err != nil {
+			return err
+		}
 
-	// shouldn't be possible? But never say never.
-	if len(nodes) == 0 {
-		return fmt.Errorf("novel error 1: please report")
+		if !json.SaneValueStart(c) {
+			return errBadStreamValue(c, p.where())
+		}
+		if c != '[' && p.options.ExpectArray {
+			return errNotArrayWas(json.TypeOf(c), p.where())
+		}
+
+		if c == '[' {
+			// what we've written so far has to go first:
+			err = finishOut()
+			if err == nil {
+				err = p.handleArray(js)
+			}
+		} else {
+			err = p.writeStreamValue(out, js, c)
+		}
+		if err != nil {
+			return err
+		}
 	}
+}
 
-	next, nodes := nodes[0], nodes[1:]
-	if next == "" {
-		return errBlankPath()
+// writeStreamValue writes out the value under the cursor as a record, or
+// records with -entries or -unwind.
+func (p processor) writeStreamValue(out io.Writer, js *json.JSON, clue byte) error {
+	if clue == '{' && p.options.Entries {
+		return p.writeEntries(out, js)
+	}
+	if p.options.Unwind != "" {
+		_, err := p.writeUnwound(out, js, p.where())
+		if err == io.EOF {
+			return errNonArrayEOF(json.TypeOf(clue), p.where())
+		}
+		return err
 	}
 
-	found, err := scan.ScanForKeyValue(next)
+	err := p.contextsFound(p.where())
 	if err != nil {
 		return err
 	}
-	if !found {
-		return errBadPath(next)
+
+	n, err := p.writeRecord(out, js)
+	if err == io.EOF {
+		return errNonArrayEOF(json.TypeOf(clue), p.where())
+	}
+	if err != nil || n == 0 {
+		return err
+	}
+
+	_, err = out.Write([]byte("\n"))
+	return err
+}
+
+// trailing is what we do with anything after the top level array.
+func (p processor) trailing() string {
+	if p.options.Trailing != "" {
+		return p.options.Trailing
+	}
+	if p.options.Strict {
+		return options.TrailingError
+	}
+	return options.TrailingIgnore
+}
+
+func (p processor) handlePath(branches []branch, scan *json.JSON) error {
+	_, err := scan.Next()
+	if err == io.EOF {
+		return errNoJSON(p.where())
 	}
 
+	// follow the path(s) through each value in what may be a JSON stream:
+	for err == nil {
+		p.contexts.reset()
+		err = p.handleBranches(branches, scan)
+		if err != nil {
+			return err
+		}
+
+		_, err = scan.Next()
+	}
+
+	if err == io.EOF {
+		return nil
+	}
+	return err
+}
+
+// handlePathNodes follows the path nodes from the value under the cursor,
+// consuming all of the value as there may be more to come after it.
+func (p processor) handlePathNodes(nodes path.Path, scan *json.JSON) error {
+	return json.Classify(p.followPathNodes(nodes, scan), p.where())
+}
+
+func (p processor) followPathNodes(nodes path.Path, scan *json.JSON) error {
+
 	if len(nodes) == 0 {
+		if p.context != nil {
+			return p.captureContext(scan)
+		}
 
 		clue, err := scan.Next()
 		if err != nil {
@@ -89,94 +244,637 @@ func (p processor) handlePathNodes(nodes []string, scan *json.JSON) error {
 
 		return p.handleNonArray(scan, clue, false)
 	}
-	return p.handlePathNodes(nodes, scan)
+
+	next, nodes := nodes[0], nodes[1:]
+
+	var found bool
+	var err error
+
+	step := next
+	if next.Kind == path.Token {
+		step, err = p.resolveToken(next, scan)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch step.Kind {
+	case path.Slice:
+		return p.handlePathSlice(step, nodes, scan)
+	case path.Wildcard:
+		return p.handlePathWildcard(nodes, scan)
+	case path.Descendant:
+		return p.handlePathDescendant(step, nodes, scan)
+	case path.Index:
+		found, err = scan.ScanForIndex(step.Index)
+	default:
+		if p.duplicatesMatter() {
+			return p.handlePathDuplicates(next, step, nodes, scan)
+		}
+		found, err = scan.ScanForKeyValue(step.Key)
+	}
+
+	if err != nil {
+		return err
+	}
+	if !found {
+		return p.missing(next)
+	}
+
+	err = p.down(step).handlePathNodes(nodes, scan)
+	if err != nil {
+		return err
+	}
+
+	if step.Kind == path.Index {
+		return scan.SkipRest('[')
+	}
+	return scan.SkipRest('{')
+}
+
+func (p processor) handlePathSlice(slice path.Step, nodes path.Path, scan *json.JSON) error {
+	it, err := scan.IterArray()
+	if err != nil {
+		return err
+	}
+
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return err
+		}
+
+		i := it.Index()
+		if slice.End >= 0 && i >= slice.End {
+			return scan.SkipRest('[')
+		}
+
+		if i < slice.Index {
+			err = scan.Skip()
+		} else {
+			err = p.downIndex(i).handlePathNodes(nodes, scan)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (p processor) handlePathWildcard(nodes path.Path, scan *json.JSON) error {
+	clue, err := scan.Next()
+	if err != nil {
+		return err
+	}
+
+	if clue == '[' {
+		return p.handlePathSlice(path.Step{Kind: path.Slice, End: -1}, nodes, scan)
+	}
+
+	if clue != '{' {
+		return errWildcardOn(clue, p.where(), p.at)
+	}
+
+	it, err := scan.IterObject()
+	if err != nil {
+		return err
+	}
+
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return err
+		}
+
+		key := path.Step{Kind: path.Key, Key: string(it.Key())}
+		err = p.down(key).handlePathNodes(nodes, scan)
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handlePathDescendant searches everything under the cursor for the key in
+// the descendant step, following the rest of the path from each match. We
+// don't search within the values we match.
+func (p processor) handlePathDescendant(descendant path.Step, nodes path.Path, scan *json.JSON) error {
+	clue, err := scan.Next()
+	if err != nil {
+		return err
+	}
+
+	if clue == '[' {
+		it, err := scan.IterArray()
+		if err != nil {
+			return err
+		}
+
+		for {
+			more, err := it.Next()
+			if err != nil || !more {
+				return err
+			}
+
+			err = p.downIndex(it.Index()).handlePathDescendant(descendant, nodes, scan)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if clue != '{' {
+		return scan.Skip()
+	}
+
+	it, err := scan.IterObject()
+	if err != nil {
+		return err
+	}
+
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return err
+		}
+
+		key := path.Step{Kind: path.Key, Key: string(it.Key())}
+		if key.Key == descendant.Key {
+			err = p.down(key).handlePathNodes(nodes, scan)
+		} else {
+			err = p.down(key).handlePathDescendant(descendant, nodes, scan)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// handlePathDuplicates looks up a key in the whole of the object under the
+// cursor, for the -duplicate-keys policies that care about more than the
+// first occurrence. For "last" we have to hold on to each occurrence's value
+// until we know there's not another.
+func (p processor) handlePathDuplicates(node, key path.Step, nodes path.Path, scan *json.JSON) error {
+	found, err := scan.ScanForKeyValue(key.Key)
+	if err != nil {
+		return err
+	}
+	if !found {
+		return p.missing(node)
+	}
+
+	var last *bytes.Buffer
+	for n := 0; found; n++ {
+		switch p.options.DuplicateKeys {
+		case options.DuplicateLast:
+			last = &bytes.Buffer{}
+			_, err = scan.WriteCurrentTo(last, true)
+		case options.DuplicateError:
+			if n > 0 {
+				return errDuplicateKey(p.down(key).where())
+			}
+			err = p.down(key).handlePathNodes(nodes, scan)
+		default:
+			err = p.down(key).handlePathNodes(nodes, scan)
+		}
+		if err != nil {
+			return err
+		}
+
+		found, err = scan.ScanForNextKeyValue(key.Key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if last == nil {
+		return nil
+	}
+	return p.down(key).handlePathNodes(nodes, json.New(last))
+}
+
+// duplicatesMatter is true when we need to look past the first occurrence of
+// a key, see -duplicate-keys.
+func (p processor) duplicatesMatter() bool {
+	switch p.options.DuplicateKeys {
+	case options.DuplicateLast, options.DuplicateError, options.DuplicateAll:
+		return true
+	}
+	return false
+}
+
+// resolveToken turns a JSON Pointer token into a key or index step depending
+// on what we're looking at. A token that can't be an index gives us an index
+// that won't be found.
+func (p processor) resolveToken(token path.Step, scan *json.JSON) (path.Step, error) {
+	clue, err := scan.Next()
+	if err != nil {
+		return token, err
+	}
+
+	if clue != '[' {
+		return path.Step{Kind: path.Key, Key: token.Key}, nil
+	}
+
+	return path.Step{Kind: path.Index, Index: token.Index}, nil
+}
+
+// missing deals with a path node that doesn't exist, according to the
+// -missing option.
+func (p processor) missing(node path.Step) error {
+	if p.context != nil {
+		return p.missingContext(node)
+	}
+
+	err := errBadPath(node.String(), p.where())
+
+	switch p.options.Missing {
+	case options.MissingSkip:
+		return p.skipped(err)
+	case options.MissingNull:
+		return p.down(node).writeLiteral([]byte("null"))
+	case options.MissingDefault:
+		return p.down(node).writeLiteral([]byte(p.options.MissingDefault))
+	}
+
+	return err
+}
+
+// skipped reports something we've skipped over.
+func (p processor) skipped(e error) error {
+	if p.warn == nil {
+		return nil
+	}
+
+	return report(p.warn, p.options.ErrorFormat, p.name, errSkipped(p.name, p.atString(), e))
+}
+
+// down gives us a processor which has moved down the path by one step.
+func (p processor) down(s path.Step) processor {
+	p.at = append(p.at[:len(p.at):len(p.at)], s)
+	return p
+}
+
+func (p processor) downIndex(i int) processor {
+	return p.down(path.Step{Kind: path.Index, Index: i})
 }
 
 func (p processor) prepOut() (w io.Writer, finishOut func() error) {
 	if p.buffered {
-		bw := bufio.NewWriter(p.out)
+		bw := bufio.NewWriter(p.output())
 		return bw, bw.Flush
 	}
-	return p.out, func() error { return nil }
+	return p.output(), func() error { return nil }
+}
+
+// output is where our records go, before any buffering.
+func (p processor) output() io.Writer {
+	if p.options.NormalizeNumbers {
+		return json.NormalizeNumbers(p.out)
+	}
+	return p.out
 }
 
 func (p processor) handleArray(js *json.JSON) error {
+	out, finishOut := p.prepOut()
+
+	skip := p.options.OnError == options.OnErrorSkip
+	var hold *holding
+	if skip || p.options.Salvage {
+		hold = &holding{raw: skip}
+		defer js.EndCapture()
+	}
+
+	err := p.unpackArray(out, js, hold, 1)
+	if err != nil && p.options.Salvage {
+		err = p.salvage(hold, err, finishOut)
+	}
+	if err != nil {
+		return err
+	}
+
+	return finishOut()
+}
+
+// unpackArray writes out each value of the array under the cursor, level is
+// how many arrays deep we are (see -depth).
+func (p processor) unpackArray(out io.Writer, js *json.JSON, hold *holding, level int) error {
 
 	// shift the cursor from the start of the array:
 	js.MoveOff()
 
-	out, finishOut := p.prepOut()
-
-	arrayIDX := 0
-	for {
-		c, err := js.Next()
+	for arrayIDX := 0; ; arrayIDX++ {
+		more, err := p.arrayValue(out, js, arrayIDX, hold, level)
+		if err != nil && p.options.OnError == options.OnErrorSkip {
+			more, err = p.skipBad(out, js, arrayIDX, hold, err)
+		}
 		if err != nil {
-			return arrayNextError(arrayIDX, err)
+			return err
+		}
+		if !more {
+			return nil
 		}
+	}
+}
 
-		if !json.SaneValueStart(c) {
-			return errBadArrayValueStart(c, arrayIDX)
+// unpackDeeper is true if we unpack the arrays we find at level, see -depth.
+func (p processor) unpackDeeper(level int) bool {
+	return p.options.Depth == options.DepthAll || level < p.options.Depth
+}
+
+// arrayValue writes out the value at index of the array we're in, and moves
+// past the ',' after it. It's false at the end of the array.
+func (p processor) arrayValue(out io.Writer, js *json.JSON, arrayIDX int, hold *holding, level int) (bool, error) {
+	c, err := js.Next()
+	hold.start(js)
+	if err != nil {
+		return false, arrayNextError(p.whereIndex(arrayIDX), err)
+	}
+
+	if c == ']' && arrayIDX == 0 {
+		// empty array
+		js.MoveOff()
+		return false, nil
+	}
+
+	if !json.SaneValueStart(c) {
+		return false, errBadArrayValueStart(c, p.whereIndex(arrayIDX))
+	}
+
+	if c == '[' && p.unpackDeeper(level) {
+		err = p.downIndex(arrayIDX).unpackArray(out, js, hold, level+1)
+		if err != nil {
+			return false, err
 		}
+		return p.arrayNext(out, js, arrayIDX, hold)
+	}
 
-		n, err := js.WriteCurrentTo(out, true)
+	w := hold.writer(out)
+	if p.options.Unwind != "" {
+		n, err := p.writeUnwound(w, js, p.whereIndex(arrayIDX))
+		if _, typed := json.WhereOf(err); typed {
+			hold.drop()
+			return false, err
+		}
+		if err != nil {
+			hold.drop()
+			return false, arrayJSONErr(p.whereIndex(arrayIDX), err)
+		}
+		hold.wrote(n)
+	} else {
+		err = p.contextsFound(p.whereIndex(arrayIDX))
+		if err != nil {
+			hold.drop()
+			return false, err
+		}
 
+		n, err := p.writeRecord(w, js)
 		if err != nil {
-			return arrayJSONErr(err)
+			hold.drop()
+			return false, arrayJSONErr(p.whereIndex(arrayIDX), err)
 		}
 		if n > 0 {
-			_, err := out.Write([]byte("\n"))
+			_, err := w.Write([]byte("\n"))
 			if err != nil {
-				return arrayJSONErr(err)
+				return false, arrayJSONErr(p.whereIndex(arrayIDX), err)
 			}
+			hold.wrote(1)
 		}
 		if n == 0 {
-			break
+			return false, nil
 		}
+	}
 
-		c, err = js.Next()
+	// when salvaging a number or a literal isn't done until we've seen
+	// what's after it, anything else is done at its closing character:
+	if !p.options.Salvage || c == '{' || c == '[' || c == '"' {
+		err = hold.flush(out)
 		if err != nil {
-			return arrayNextError(arrayIDX, err)
+			return false, arrayJSONErr(p.whereIndex(arrayIDX), err)
 		}
+	}
 
-		if c == ']' {
-			break
-		}
-		if c == ',' {
-			js.MoveOff()
-		} else {
-			return fmt.Errorf("unexpected character: %c", c)
-		}
+	return p.arrayNext(out, js, arrayIDX, hold)
+}
 
-		arrayIDX++
+// arrayNext moves past the ',' after the value at index, it's false at the
+// end of the array.
+func (p processor) arrayNext(out io.Writer, js *json.JSON, arrayIDX int, hold *holding) (bool, error) {
+	c, err := js.Next()
+	hold.capture(js)
+	if err != nil && p.options.Salvage && !hold.held() {
+		// the value's all there, it's the one after it that was cut off:
+		hold.start(js)
+		return false, arrayNextError(p.whereIndex(arrayIDX+1), err)
+	}
+	if err != nil {
+		return false, arrayNextError(p.whereIndex(arrayIDX), err)
 	}
 
-	return finishOut()
+	if c != ']' && c != ',' {
+		return false, errUnexpectedCharacter(c, p.whereIndex(arrayIDX))
+	}
+
+	err = hold.flush(out)
+	if err != nil {
+		return false, arrayJSONErr(p.whereIndex(arrayIDX), err)
+	}
+
+	js.MoveOff()
+	return c == ',', nil
+}
+
+// holding is what we hold on to for the value we're on, when we can't write
+// it out straight away, see -on-error=skip and -salvage.
+type holding struct {
+	// record is the record we're writing, which we hold on to until we
+	// know it's good
+	record bytes.Buffer
+	// raw is the input from where the value started, when we need it, at
+	// the position in rawAt
+	raw   bool
+	input bytes.Buffer
+	rawAt json.Position
+	// at is where the value started
+	at json.Position
+	// records is how many we've written, and pending how many there are in
+	// record
+	records int
+	pending int
+}
+
+// start is called with the cursor at the start of a value.
+func (h *holding) start(js *json.JSON) {
+	if h == nil {
+		return
+	}
+	h.at = js.Position()
+	h.capture(js)
+}
+
+// capture starts keeping the input from the cursor, in case what's there is
+// bad.
+func (h *holding) capture(js *json.JSON) {
+	if h == nil || !h.raw {
+		return
+	}
+	h.input.Reset()
+	h.rawAt = js.Position()
+	js.Capture(&h.input)
+}
+
+// writer is where to write the record for the value we're on.
+func (h *holding) writer(out io.Writer) io.Writer {
+	if h == nil {
+		return out
+	}
+	h.record.Reset()
+	h.pending = 0
+	return &h.record
+}
+
+// wrote notes that we've written records to the writer.
+func (h *holding) wrote(records int) {
+	if h != nil {
+		h.pending += records
+	}
+}
+
+// held is true if we've a record we haven't written out yet.
+func (h *holding) held() bool {
+	return h != nil && h.record.Len() > 0
+}
+
+// drop the record we've been holding on to.
+func (h *holding) drop() {
+	if h != nil {
+		h.record.Reset()
+		h.pending = 0
+	}
+}
+
+// flush writes out the record we've been holding on to, if there is one.
+func (h *holding) flush(out io.Writer) error {
+	if h == nil || h.record.Len() == 0 {
+		return nil
+	}
+	_, err := out.Write(h.record.Bytes())
+	h.record.Reset()
+	h.records += h.pending
+	h.pending = 0
+	return err
+}
+
+// skipBad skips the value that gave us err, if it's bad JSON we can get past,
+// reporting what we skipped. It's false at the end of the array.
+func (p processor) skipBad(out io.Writer, js *json.JSON, arrayIDX int, hold *holding, err error) (bool, error) {
+	var syntax *json.SyntaxError
+	if !errors.As(err, &syntax) {
+		return false, err
+	}
+
+	// a nested array we unpacked (see -depth) had its go at skipping this:
+	if syntax.Path != p.atString() {
+		return false, err
+	}
+
+	// the array we're in is one deeper than the path we've followed:
+	if js.SkipBad(len(p.at)+1) != nil {
+		return false, err
+	}
+
+	// if there's a record we're holding on to the problem came after it:
+	ferr := hold.flush(out)
+	if ferr != nil {
+		return false, ferr
+	}
+
+	rerr := p.reject(hold, p.whereIndex(arrayIDX), err)
+	if rerr != nil {
+		return false, rerr
+	}
+
+	c, _ := js.Next()
+	js.MoveOff()
+	return c == ',', nil
+}
+
+// reject reports a value we've skipped over, and writes it to -reject-file.
+func (p processor) reject(hold *holding, w json.Where, e error) error {
+	raw := hold.input.Bytes()
+	offset := hold.rawAt.Offset + int64(len(raw)-len(bytes.TrimLeft(raw, " \t\r\n")))
+	raw = bytes.TrimSpace(raw)
+
+	err := p.skipped(e)
+	if err != nil || p.rejects == nil {
+		return err
+	}
+
+	r := rejectRecord{
+		Offset:  offset,
+		Index:   w.Index,
+		Path:    w.Path,
+		Message: e.Error(),
+	}
+	if p.name != "" {
+		r.File = &p.name
+	}
+	if utf8.Valid(raw) {
+		r.Raw = string(raw)
+	} else {
+		r.RawBase64 = raw
+	}
+
+	b, err := stdjson.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.rejects, "%s\n", b)
+	return err
+}
+
+// salvage deals with the input being cut off part way through an array, we
+// keep what we've got and give back errPartial. Other errors are returned as
+// they are.
+func (p processor) salvage(hold *holding, err error, finishOut func() error) error {
+	var eof *json.UnexpectedEOFError
+	if !errors.As(err, &eof) {
+		return err
+	}
+
+	ferr := finishOut()
+	if ferr != nil {
+		return ferr
+	}
+	return errPartial(hold.records, eof.Index, hold.at.Offset, err)
+}
+
+// rejectRecord is a value we skipped, as we write it to -reject-file.
+type rejectRecord struct {
+	File    *string `json:"file"`
+	Offset  int64   `json:"offset"`
+	Index   int     `json:"index"`
+	Path    string  `json:"path"`
+	Message string  `json:"message"`
+	// Raw is the value as it was, unless it's not valid UTF-8 in which
+	// case it's in RawBase64
+	Raw       string `json:"raw,omitempty"`
+	RawBase64 []byte `json:"raw_base64,omitempty"`
 }
 
 func (p processor) handleNonArray(j *json.JSON, clue byte, topLevel bool) error {
 	out, finishOut := p.prepOut()
 
 	if p.options.ExpectArray {
-		return errNotArrayWas(guessJSONType(j.Peek()))
+		return errNotArrayWas(json.TypeOf(j.Peek()), p.where())
 	}
 	if !json.SaneValueStart(clue) {
-		return errPathLeadToBadValue(clue, p.options.Path)
+		return errPathLeadToBadValue(clue, p.where())
 	}
 
 	for {
-		n, err := j.WriteCurrentTo(out, true)
+		err := p.writeStreamValue(out, j, clue)
 		if err != nil {
-			if err == io.EOF {
-				return errNonArrayEOF(guessJSONType(clue))
-			}
 			return err
 		}
-		if n > 0 {
-			_, err := out.Write([]byte("\n"))
-			if err != nil {
-				return err
-			}
-		}
 
 		// we don't continue if we scanned down to this level
 		if !topLevel {
@@ -185,7 +883,7 @@ func (p processor) handleNonArray(j *json.JSON, clue byte, topLevel bool) error
 
 		// okay now we're in some kind of JSON stream like
 		// NDJSON, so look for the next thing
-		clue, err := j.Next()
+		clue, err = j.Next()
 		if err == io.EOF {
 			break
 		}
@@ -201,84 +899,246 @@ func (p processor) handleNonArray(j *json.JSON, clue byte, topLevel bool) error
 	return finishOut()
 }
 
-func guessJSONType(clue byte) string {
+// atString is the path we've followed to get here, in the form it was given
+// to us.
+func (p processor) atString() string {
+	if p.options.Pointer != "" {
+		return p.at.Pointer()
+	}
+	return p.at.String()
+}
+
+// where is where we've got to, for errors. If the last step of the path was
+// into an array that's our index.
+func (p processor) where() json.Where {
+	index := -1
+	if len(p.at) > 0 && p.at[len(p.at)-1].Kind == path.Index {
+		index = p.at[len(p.at)-1].Index
+	}
+	return json.Where{Index: index, Path: p.atString()}
+}
+
+func (p processor) whereIndex(i int) json.Where {
+	w := p.where()
+	w.Index = i
+	return w
+}
+
+// writeRecord writes out the value under the cursor, less the newline.
+func (p processor) writeRecord(out io.Writer, js *json.JSON) (int, error) {
+	err := p.openTag(out)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int
+	if p.contexts != nil {
+		n, err = p.writeWithContext(out, js)
+	} else {
+		n, err = js.WriteCurrentTo(out, true)
+	}
+	if err != nil {
+		return n, err
+	}
+
+	return n, p.closeTag(out)
+}
+
+// writeLiteral writes out a record of our own making.
+func (p processor) writeLiteral(value []byte) error {
+	out := p.output()
+
+	err := p.openTag(out)
+	if err == nil {
+		_, err = out.Write(value)
+	}
+	if err == nil {
+		err = p.closeTag(out)
+	}
+	if err == nil {
+		_, err = out.Write([]byte("\n"))
+	}
+	return err
+}
 
-	switch clue {
-	case '{':
-		return "object"
-	case '"':
-		return "string"
-	case '[':
-		return "array"
-	case 't':
-		return "boolean"
-	case 'f':
-		return "boolean"
-	case 'n':
-		return "null"
+func (p processor) tagging() bool {
+	return p.tag || p.options.TagPath
+}
+
+// openTag starts wrapping a record with the path it was found at, if we're
+// doing that.
+func (p processor) openTag(out io.Writer) error {
+	if !p.tagging() {
+		return nil
+	}
+
+	tag, err := stdjson.Marshal(p.atString())
+	if err != nil {
+		return err
 	}
 
-	if (clue >= '0' && clue <= '9') || clue == '-' {
-		return "number"
+	_, err = fmt.Fprintf(out, `{"path":%s,"value":`, tag)
+	return err
+}
+
+func (p processor) closeTag(out io.Writer) error {
+	if !p.tagging() {
+		return nil
 	}
 
-	return ""
+	_, err := out.Write([]byte("}"))
+	return err
 }
 
 func errNilInput() error {
 	return fmt.Errorf("nil input")
 }
 
-func errNotArrayWas(t string) error {
-	return fmt.Errorf("expected structure to be an array but found: %s", t)
+func errNotArrayWas(t string, w json.Where) error {
+	return &json.TypeMismatchError{
+		Where:    w,
+		Expected: "array",
+		Found:    t,
+		Err:      fmt.Errorf("expected structure to be an array but found: %s", t),
+	}
 }
 
-func errNonArrayEOF(t string) error {
+func errNonArrayEOF(t string, w json.Where) error {
 	if t == "" {
-		return fmt.Errorf("JSON data ended prematurely")
+		return &json.UnexpectedEOFError{Where: w, Err: fmt.Errorf("JSON data ended prematurely")}
 	}
-	return fmt.Errorf("file ended before the end of value (%s)", t)
+	return &json.UnexpectedEOFError{Where: w, Err: fmt.Errorf("file ended before the end of value (%s)", t)}
+}
+
+func errBadPath(chunk string, w json.Where) error {
+	return &json.PathNotFoundError{Where: w, Node: chunk}
+}
+
+func errDuplicateKey(w json.Where) error {
+	return &json.SyntaxError{Where: w, Err: fmt.Errorf("path (%s) found more than once, the key is repeated", w.Path)}
+}
+
+func errSkipped(name, at string, e error) error {
+	if name == "" {
+		name = "stdin"
+	}
+	if at == "" {
+		return skippedErr{fmt.Errorf("skipped %s: %w", name, e)}
+	}
+	return skippedErr{fmt.Errorf("skipped %s at (%s): %w", name, at, e)}
+}
+
+func errPartial(records, index int, offset int64, e error) error {
+	return partialErr{records, index, offset, e}
 }
 
-func errBadPath(chunk string) error {
-	return fmt.Errorf("path node did not exist: %s", chunk)
+// partialErr is input which was cut off part way through an array, which
+// -salvage has kept what it could of.
+type partialErr struct {
+	records int
+	// index and offset of the value that was cut off
+	index  int
+	offset int64
+	err    error
 }
 
-func errBlankPath() error {
-	return fmt.Errorf("bad blank path node, did you have a double dot?")
+func (e partialErr) Error() string {
+	return fmt.Sprintf(
+		"input was cut off part way through the array, salvaged %d records before array index %d (byte offset %d)",
+		e.records, e.index, e.offset,
+	)
 }
-func errPathLeadToBadValue(start byte, path string) error {
-	t := guessJSONType(start)
+
+func (e partialErr) Unwrap() error {
+	return e.err
+}
+
+// skippedErr is an error we've carried on past.
+type skippedErr struct {
+	err error
+}
+
+func (e skippedErr) Error() string {
+	return e.err.Error()
+}
+
+func (e skippedErr) Unwrap() error {
+	return e.err
+}
+
+func errPathLeadToBadValue(start byte, w json.Where) error {
+	t := json.TypeOf(start)
 
 	if t != "" {
-		return fmt.Errorf("path (%s) lead to %s not an object", path, t)
+		return &json.TypeMismatchError{
+			Where:    w,
+			Expected: "object",
+			Found:    t,
+			Err:      fmt.Errorf("path (%s) lead to %s not an object", w.Path, t),
+		}
 	}
 
-	return fmt.Errorf("path (%s) lead to bad value start: %c", path, start)
+	return &json.SyntaxError{Where: w, Err: fmt.Errorf("path (%s) lead to bad value start: %c", w.Path, start)}
+}
+
+func errWildcardOn(clue byte, w json.Where, at path.Path) error {
+	t := json.TypeOf(clue)
+	found := t
+	if t == "" {
+		t = fmt.Sprintf("bad value start: %c", clue)
+	}
+
+	var err error
+	if len(at) == 0 {
+		err = fmt.Errorf("path wildcard needs an object or array but found %s", t)
+	} else {
+		err = fmt.Errorf("path wildcard needs an object or array but (%s) lead to %s", at, t)
+	}
+	return &json.TypeMismatchError{Where: w, Expected: "object or array", Found: found, Err: err}
+}
+
+func errBadArrayValueStart(start byte, w json.Where) error {
+	return &json.SyntaxError{
+		Where: w,
+		Err:   fmt.Errorf("at array index %d found something which doesn't look like a JSON value, starts with: %c", w.Index, start),
+	}
+}
+
+func errTrailing(c byte, w json.Where) error {
+	return &json.SyntaxError{
+		Where: w,
+		Err:   fmt.Errorf("found %c after the end of the top level array, see -%s", c, options.OptTrailing),
+	}
+}
+
+func errBadStreamValue(c byte, w json.Where) error {
+	return &json.SyntaxError{
+		Where: w,
+		Err:   fmt.Errorf("found something which doesn't look like a JSON value in the stream, starts with: %c", c),
+	}
 }
 
-func errBadArrayValueStart(start byte, index int) error {
-	return fmt.Errorf("at array index %d found something which doesn't look like a JSON value, starts with: %c", index, start)
+func errUnexpectedCharacter(c byte, w json.Where) error {
+	return &json.SyntaxError{Where: w, Err: fmt.Errorf("unexpected character: %c", c)}
 }
 
-func arrayNextError(index int, e error) error {
+func arrayNextError(w json.Where, e error) error {
 	if e == io.EOF {
-		return errArrayEOF(index)
+		return errArrayEOF(w)
 	}
-	// TODO: process this?
-	return e
+	return json.Classify(e, w)
 }
 
-func errArrayEOF(index int) error {
-	return fmt.Errorf("at array index %d we ran out of data", index)
+func errArrayEOF(w json.Where) error {
+	return &json.UnexpectedEOFError{Where: w, Err: fmt.Errorf("at array index %d we ran out of data", w.Index)}
 }
 
-func errNoJSON() error {
-	return fmt.Errorf("no JSON data found")
+func errNoJSON(w json.Where) error {
+	return &json.UnexpectedEOFError{Where: w, Err: fmt.Errorf("no JSON data found")}
 }
 
-func arrayJSONErr(e error) error {
-	return fmt.Errorf("array JSON decode error: %w", e)
+func arrayJSONErr(w json.Where, e error) error {
+	return json.Classify(fmt.Errorf("array JSON decode error: %w", e), w)
 }
 
 func peekErr(e error) error {
@@ -286,5 +1146,8 @@ func peekErr(e error) error {
 }
 
 func errArrayInStream() error {
-	return fmt.Errorf("Found an array in what seemed to be a JSON stream. Consider using -%s", options.OptPreserveArray)
+	return fmt.Errorf(
+		"Found an array in what seemed to be a JSON stream. Consider using -%s or -%s",
+		options.OptPreserveArray, options.OptFlattenStream,
+	)
 }
@@ -2,13 +2,14 @@ package main
 
 import (
 	"bytes"
-	"fmt"
+	"errors"
 	"io"
 	"strings"
 	"testing"
 
 	"github.com/draxil/json2nd/internal/json"
 	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -38,18 +39,76 @@ func TestProcessor(t *testing.T) {
 				ExpectArray: true,
 			},
 			in:     sreader("{}"),
-			expErr: errNotArrayWas("object"),
+			expErr: errNotArrayWas("object", at("")),
 		},
 		{
 			name: "non-array + leading whitespace + tolerant",
 			in:   sreader("   \r\n{}"),
 			exp:  "{}\n",
 		},
+		{
+			name: "strings ending in a backslash",
+			in:   sreader(`[{"dir":"C:\\"}, {"dir":"D:\\x\\"}]`),
+			exp:  `{"dir":"C:\\"}` + "\n" + `{"dir":"D:\\x\\"}` + "\n",
+		},
+		{
+			name: "path past a string ending in a backslash",
+			opts: options.Set{
+				Paths: []string{"files"},
+			},
+			in:  sreader(`{"dir":"C:\\", "files":["a\\"]}`),
+			exp: `"a\\"` + "\n",
+		},
+		{
+			name: "path through escaped keys",
+			opts: options.Set{
+				Paths: []string{"café.id"},
+			},
+			in:  sreader(`{"cafe":{}, "caf\u00e9":{"\u0069\u0064":[1]}}`),
+			exp: "1\n",
+		},
+		{
+			name: "wildcard gives decoded keys",
+			opts: options.Set{
+				Paths:   []string{"*"},
+				TagPath: true,
+			},
+			in:  sreader(`{"caf\u00e9":1, "a\\.b":2}`),
+			exp: `{"path":"café","value":1}` + "\n" + `{"path":"\"a\\\\.b\"","value":2}` + "\n",
+		},
+		{
+			name: "strict",
+			opts: options.Set{
+				Strict: true,
+			},
+			in:  sreader(`[{"a":[1, 2.5e-3]}, "x\u00e9", null]`),
+			exp: `{"a":[1, 2.5e-3]}` + "\n" + `"x\u00e9"` + "\n" + "null\n",
+		},
+		{
+			name: "strict, bad number",
+			opts: options.Set{
+				Strict: true,
+			},
+			in:     sreader(`[1, 1.....4]`),
+			exp:    "1\n1.",
+			expErr: arrayJSONErr(atIndex("", 1), json.ErrInvalidJSON{Offset: 6, Problem: "bad number, expected a digit after the decimal point but found '.'"}),
+		},
+		{
+			name: "strict, bad object",
+			opts: options.Set{
+				Strict:  true,
+				Paths:   []string{"a"},
+				TagPath: true,
+			},
+			in:     sreader(`{"a":[{"x" 1}]}`),
+			exp:    `{"path":"a","value":{"x" `,
+			expErr: arrayJSONErr(atIndex("a", 0), json.ErrInvalidJSON{Offset: 11, Problem: "expected ':' but found '1'"}),
+		},
 		{
 			name:   "just whitespace + tolerant",
 			in:     sreader("           "),
 			exp:    "",
-			expErr: errNoJSON(),
+			expErr: errNoJSON(at("")),
 		},
 		{
 			name: "just whitespace not tolerant",
@@ -57,7 +116,7 @@ func TestProcessor(t *testing.T) {
 			opts: options.Set{
 				ExpectArray: true,
 			},
-			expErr: errNoJSON(),
+			expErr: errNoJSON(at("")),
 		},
 		{
 			name: "simple use-case",
@@ -75,10 +134,34 @@ func TestProcessor(t *testing.T) {
 			in:   sreader(`[[{"a":1},{"b":2}], [2]]`),
 			exp:  `[{"a":1},{"b":2}]` + "\n" + `[2]` + "\n",
 		},
+		{
+			name: "empty array",
+			in:   sreader(` [ ] `),
+			exp:  "",
+		},
 		{
 			name: "number array",
-			in:   sreader(`    [1, 2, 3, 4, 5.432, 1.e-23] `),
-			exp:  "1\n2\n3\n4\n5.432\n1.e-23\n",
+			in:   sreader(`    [1, 2, 3, 4, 5.432, 1e-23] `),
+			exp:  "1\n2\n3\n4\n5.432\n1e-23\n",
+		},
+		{
+			name: "number array with zeroes",
+			in:   sreader(`[0, 1, -0, 0.5, -0.5e+10]`),
+			exp:  "0\n1\n-0\n0.5\n-0.5e+10\n",
+		},
+		{
+			name: "normalized numbers",
+			opts: options.Set{
+				NormalizeNumbers: true,
+			},
+			in:  sreader(`[1E+5, {"E+":[2.5e+3]}, "1E+5"]`),
+			exp: "1e5\n" + `{"E+":[2.5e3]}` + "\n" + `"1E+5"` + "\n",
+		},
+		{
+			name:   "number array with a bad number",
+			in:     sreader(`[1, 1.e-23]`),
+			exp:    "1\n",
+			expErr: arrayJSONErr(atIndex("", 1), json.ErrBadNumber{At: 2, Problem: "expected a digit after the decimal point but found 'e'"}),
 		},
 		{
 			name: "bool and null array",
@@ -89,79 +172,378 @@ func TestProcessor(t *testing.T) {
 			name: "bad path",
 			in:   sreader(`{}`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
-			expErr: errBadPath("something"),
+			expErr: errBadPath("something", at("")),
 		},
 		{
 			name: "bad path one down",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "something.else",
+				Paths: []string{"something.else"},
 			},
-			expErr: errBadPath("else"),
+			expErr: errBadPath("else", at("something")),
 		},
 		{
 			name: "good simple path to string array",
 			in:   sreader(`{"something":{"else":["one", "two"]}}`),
 			opts: options.Set{
-				Path: "something.else",
+				Paths: []string{"something.else"},
 			},
 			exp: `"one"` + "\n" + `"two"` + "\n",
 		},
+		{
+			name: "path with an index",
+			in:   sreader(`{"pages":[{"items":[1,2]},{"items":[3,4]}]}`),
+			opts: options.Set{
+				Paths: []string{"pages[1].items"},
+			},
+			exp: "3\n4\n",
+		},
+		{
+			name: "path with an index, nested arrays",
+			in:   sreader(`{"x":[[1,2],[[3,4],[5,6]]]}`),
+			opts: options.Set{
+				Paths: []string{"x[1][0]"},
+			},
+			exp: "3\n4\n",
+		},
+		{
+			name: "path starting with an index",
+			in:   sreader(`[{"a":1}, {"a":[2]}]`),
+			opts: options.Set{
+				Paths: []string{"[1].a"},
+			},
+			exp: "2\n",
+		},
+		{
+			name: "path with an index past the end",
+			in:   sreader(`{"pages":[{"items":[1,2]}]}`),
+			opts: options.Set{
+				Paths: []string{"pages[1].items"},
+			},
+			expErr: errBadPath("[1]", at("pages")),
+		},
+		{
+			name: "path with an index on an object",
+			in:   sreader(`{"pages":{}}`),
+			opts: options.Set{
+				Paths: []string{"pages[1]"},
+			},
+			expErr: json.Classify(json.ErrScanNotArray{On: '{'}, at("pages")),
+		},
+		{
+			name: "path with a slice",
+			in:   sreader(`{"pages":[{"items":[1]},{"items":[2, 3]},{"items":[]},{"items":[4]},{"items":[5]}]}`),
+			opts: options.Set{
+				Paths: []string{"pages[1:4].items"},
+			},
+			exp: "2\n3\n4\n",
+		},
+		{
+			name: "path with an open slice",
+			in:   sreader(`{"pages":[{"x":{"items":[1]}},{"items":{"x":2}, "z":[]},{"items":{"x":3}}]}`),
+			opts: options.Set{
+				Paths: []string{"pages[1:].items"},
+			},
+			exp: `{"x":2}` + "\n" + `{"x":3}` + "\n",
+		},
+		{
+			name: "path with slices of slices",
+			in:   sreader(`[[1,2,3],[4,5,6],[7,8,9]]`),
+			opts: options.Set{
+				Paths: []string{"[1:][:2]"},
+			},
+			exp: "4\n5\n7\n8\n",
+		},
+		{
+			name: "path with a slice, key missing from an element",
+			in:   sreader(`{"pages":[{"items":[1]},{"other":[2]}]}`),
+			opts: options.Set{
+				Paths: []string{"pages[:].items"},
+			},
+			exp:    "1\n",
+			expErr: errBadPath("items", atIndex("pages[1]", 1)),
+		},
+		{
+			name: "path with a wildcard over an object",
+			in:   sreader(`{"data":{"a":{"records":[1,2]},"b":{"x":0,"records":[3]}}}`),
+			opts: options.Set{
+				Paths: []string{"data.*.records"},
+			},
+			exp: "1\n2\n3\n",
+		},
+		{
+			name: "path with a wildcard over an array",
+			in:   sreader(`{"data":[{"records":[1,2]},{"records":{"x":3}}]}`),
+			opts: options.Set{
+				Paths: []string{"data[*].records"},
+			},
+			exp: "1\n2\n" + `{"x":3}` + "\n",
+		},
+		{
+			name: "path with nested wildcards",
+			in:   sreader(`{"a":{"x":[[1],[2]]},"b":{"y":[[3]],"z":[]}}`),
+			opts: options.Set{
+				Paths: []string{"*.*.*"},
+			},
+			exp: "1\n2\n3\n",
+		},
+		{
+			name: "path with a wildcard, tagged",
+			in:   sreader(`{"data":{"a":{"records":[1,2]},"b":{"other":{"x":3}}}}`),
+			opts: options.Set{
+				Paths:   []string{"data.*.records"},
+				TagPath: true,
+			},
+			expErr: errBadPath("records", at("data.b")),
+			exp:    `{"path":"data.a.records","value":1}` + "\n" + `{"path":"data.a.records","value":2}` + "\n",
+		},
+		{
+			name: "path with wildcards, tagged",
+			in:   sreader(`{"data":[{"records":[1]},{"records":{"x":3}}]}`),
+			opts: options.Set{
+				Paths:   []string{"data[*].records"},
+				TagPath: true,
+			},
+			exp: `{"path":"data[0].records","value":1}` + "\n" + `{"path":"data[1].records","value":{"x":3}}` + "\n",
+		},
+		{
+			name: "path with a wildcard on a scalar",
+			in:   sreader(`{"data":12}`),
+			opts: options.Set{
+				Paths: []string{"data.*"},
+			},
+			expErr: errWildcardOn('1', at("data"), path.Path{{Kind: path.Key, Key: "data"}}),
+		},
+		{
+			name: "pointer",
+			in:   sreader(`{"app.version":{"items":[1,2]}}`),
+			opts: options.Set{
+				Pointer: "/app.version/items",
+			},
+			exp: "1\n2\n",
+		},
+		{
+			name: "pointer with escapes and a blank key",
+			in:   sreader(`{"a/b":{"":{"m~n":[1,2]}}}`),
+			opts: options.Set{
+				Pointer: "/a~1b//m~0n",
+			},
+			exp: "1\n2\n",
+		},
+		{
+			name: "pointer through an array",
+			in:   sreader(`{"pages":[{"items":[1]},{"items":[2,3]}]}`),
+			opts: options.Set{
+				Pointer: "/pages/1/items",
+			},
+			exp: "2\n3\n",
+		},
+		{
+			name: "pointer with a numeric key",
+			in:   sreader(`{"pages":{"1":{"items":[4]}}}`),
+			opts: options.Set{
+				Pointer: "/pages/1/items",
+			},
+			exp: "4\n",
+		},
+		{
+			name: "pointer with a non-index on an array",
+			in:   sreader(`{"pages":[{"items":[1]}]}`),
+			opts: options.Set{
+				Pointer: "/pages/-/items",
+			},
+			expErr: errBadPath("-", at("/pages")),
+		},
+		{
+			name: "pointer leads to non-JSON",
+			in:   sreader(`{"something":boo}`),
+			opts: options.Set{
+				Pointer: "/something",
+			},
+			expErr: errPathLeadToBadValue('b', at("/something")),
+		},
+		{
+			name: "pointer, tagged",
+			in:   sreader(`{"a/b":[{"x":[1]}]}`),
+			opts: options.Set{
+				Pointer: "/a~1b/0/x",
+				TagPath: true,
+			},
+			exp: `{"path":"/a~1b/0/x","value":1}` + "\n",
+		},
+		{
+			name: "path with quoted keys",
+			in:   sreader(`{"meta":{"k8s.io/name":{"items":[1,2]}}}`),
+			opts: options.Set{
+				Paths: []string{`meta."k8s.io/name".items`},
+			},
+			exp: "1\n2\n",
+		},
+		{
+			name: "path with escaped dots",
+			in:   sreader(`{"a.b":{"c":[1,2]}}`),
+			opts: options.Set{
+				Paths: []string{`a\.b.c`},
+			},
+			exp: "1\n2\n",
+		},
+		{
+			name: "missing quoted key",
+			in:   sreader(`{"a":{"b":{"c":[1,2]}}}`),
+			opts: options.Set{
+				Paths: []string{`"a.b".c`},
+			},
+			expErr: errBadPath(`"a.b"`, at("")),
+		},
+		{
+			name: "path with quoted keys, tagged",
+			in:   sreader(`{"a.b":{"c":[1]}}`),
+			opts: options.Set{
+				Paths:   []string{`*.c`},
+				TagPath: true,
+			},
+			exp: `{"path":"\"a.b\".c","value":1}` + "\n",
+		},
+		{
+			name: "find",
+			in: sreader(`{"id":1,"a":{"x":{"id":[2,3]},"y":[{"id":{"id":4}},{"z":"id"}]},` +
+				`"b":[[{"id":5}]]}`),
+			opts: options.Set{
+				Find: "id",
+			},
+			exp: "1\n2\n3\n" + `{"id":4}` + "\n5\n",
+		},
+		{
+			name: "find, tagged",
+			in:   sreader(`[{"a":{"id":1}},{"id":2}]`),
+			opts: options.Set{
+				Find:    "id",
+				TagPath: true,
+			},
+			exp: `{"path":"[0].a.id","value":1}` + "\n" + `{"path":"[1].id","value":2}` + "\n",
+		},
+		{
+			name: "find nothing",
+			in:   sreader(`{"a":{"b":"id"}, "c":[1,2,{}]}`),
+			opts: options.Set{
+				Find: "id",
+			},
+			exp: "",
+		},
+		{
+			name: "find in a broken document",
+			in:   sreader(`{"a":{"id":1}, "c":[1,2,{"id":2}`),
+			opts: options.Set{
+				Find: "id",
+			},
+			exp:    "1\n2\n",
+			expErr: json.Classify(io.EOF, at("")),
+		},
+		{
+			name: "path through a stream",
+			in:   sreader(`{"batch":{"events":[1,2]}}` + "\n" + `{"x":0,"batch":{"events":[3],"y":[]}}{"batch":{"events":{"z":4}}}`),
+			opts: options.Set{
+				Paths: []string{"batch.events"},
+			},
+			exp: "1\n2\n3\n" + `{"z":4}` + "\n",
+		},
+		{
+			name: "path through a stream, missing from one record",
+			in:   sreader(`{"batch":{"events":[1]}} {"batch":{}} {"batch":{"events":[3]}}`),
+			opts: options.Set{
+				Paths: []string{"batch.events"},
+			},
+			exp:    "1\n",
+			expErr: errBadPath("events", at("batch")),
+		},
+		{
+			name: "paths through a stream",
+			in:   sreader(`{"a":[1],"b":[2]} {"b":[3],"a":[4]}`),
+			opts: options.Set{
+				Paths: []string{"a", "b"},
+			},
+			exp: `{"path":"a","value":1}` + "\n" + `{"path":"b","value":2}` + "\n" +
+				`{"path":"b","value":3}` + "\n" + `{"path":"a","value":4}` + "\n",
+		},
+		{
+			name: "find through a stream",
+			in:   sreader(`{"a":{"id":1}} [{"id":2}] 3 {"id":4}`),
+			opts: options.Set{
+				Find: "id",
+			},
+			exp: "1\n2\n4\n",
+		},
+		{
+			name: "path with nothing to follow",
+			in:   sreader(`   `),
+			opts: options.Set{
+				Paths: []string{"x"},
+			},
+			expErr: errNoJSON(at("")),
+		},
+		{
+			name: "path to an empty array",
+			in:   sreader(`{"x":[]}`),
+			opts: options.Set{
+				Paths: []string{"x"},
+			},
+			exp: "",
+		},
 		{
 			name: "broken path - 1",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: ".",
+				Paths: []string{"."},
 			},
-			expErr: errBlankPath(),
+			expErr: path.SyntaxError{Path: ".", Column: 1, Err: path.ErrBlankNode},
 		},
 		{
 			name: "broken path - 2",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "something.",
+				Paths: []string{"something."},
 			},
-			expErr: errBlankPath(),
+			expErr: path.SyntaxError{Path: "something.", Column: 11, Err: path.ErrBlankNode},
 		},
 		{
 			name: "broken path - 2",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "something..",
+				Paths: []string{"something.."},
 			},
-			expErr: errBlankPath(),
+			expErr: path.SyntaxError{Path: "something..", Column: 11, Err: path.ErrBlankNode},
 		},
 		{
 			name: "broken path - 3",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "..",
+				Paths: []string{".."},
 			},
-			expErr: errBlankPath(),
+			expErr: path.SyntaxError{Path: "..", Column: 1, Err: path.ErrBlankNode},
 		},
 		{
 			name: "broken path - 4",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: " ",
+				Paths: []string{" "},
 			},
-			expErr: errBadPath(" "),
+			expErr: errBadPath(" ", at("")),
 		},
 		{
 			name: "broken path - 5",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "\u200B",
+				Paths: []string{"\u200B"},
 			},
-			expErr: errBadPath("\u200B"),
+			expErr: errBadPath("\u200B", at("")),
 		},
 		{
 			name: "path leads to non-array",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "{}\n",
 		},
@@ -169,7 +551,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to non-array + buffering",
 			in:   sreader(`{"something":{}}`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "{}\n",
 		},
@@ -177,24 +559,24 @@ func TestProcessor(t *testing.T) {
 			name: "not JSON at all (looking for path)",
 			in:   sreader("boo"),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp:    "",
-			expErr: json.ErrScanNotObject{On: 'b'},
+			expErr: json.Classify(json.ErrScanNotObject{On: 'b'}, at("")),
 		},
 		{
 			name: "path leads to non-JSON",
 			in:   sreader(`{"something":boo}`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
-			expErr: errPathLeadToBadValue('b', "something"),
+			expErr: errPathLeadToBadValue('b', at("something")),
 		},
 		{
 			name: "path leads to a number",
 			in:   sreader(`{"something":12}`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "12\n",
 		},
@@ -202,7 +584,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a space padded number",
 			in:   sreader(`{"something":  12  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "12\n",
 		},
@@ -210,7 +592,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a space padded float",
 			in:   sreader(`{"something":  12.12  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "12.12\n",
 		},
@@ -218,7 +600,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a space padded negative float",
 			in:   sreader(`{"something":  -12.12  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "-12.12\n",
 		},
@@ -226,7 +608,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a bool (true)",
 			in:   sreader(`{"something":  true  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "true\n",
 		},
@@ -234,7 +616,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a bool (false)",
 			in:   sreader(`{"something":  false  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "false\n",
 		},
@@ -242,7 +624,7 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a null",
 			in:   sreader(`{"something":  null  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "null\n",
 		},
@@ -250,17 +632,17 @@ func TestProcessor(t *testing.T) {
 			name: "path leads to a bool (true), but with garbage afterwards",
 			in:   sreader(`{"something":  truex  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp:    "",
-			expErr: json.ErrBadValue{Value: "truex"},
+			expErr: json.Classify(json.ErrBadValue{Value: "truex"}, at("something")),
 		},
 		// technically invalid JSON, but we're not a real parser ;)
 		{
 			name: "path leads to a bool (true), but with comma afterwards",
 			in:   sreader(`{"something":  true,  }`),
 			opts: options.Set{
-				Path: "something",
+				Paths: []string{"something"},
 			},
 			exp: "true\n",
 		},
@@ -269,55 +651,55 @@ func TestProcessor(t *testing.T) {
 			name: "path goes through a null",
 			in:   sreader(`{"something":  null }`),
 			opts: options.Set{
-				Path: "something.else",
+				Paths: []string{"something.else"},
 			},
-			expErr: json.ErrScanNotObject{On: 'n'},
+			expErr: json.Classify(json.ErrScanNotObject{On: 'n'}, at("something")),
 		},
 
 		{
 			name: "path goes through a bool",
 			in:   sreader(`{"something":  true }`),
 			opts: options.Set{
-				Path: "something.else",
+				Paths: []string{"something.else"},
 			},
-			expErr: json.ErrScanNotObject{On: 't'},
+			expErr: json.Classify(json.ErrScanNotObject{On: 't'}, at("something")),
 		},
 
 		{
 			name: "path goes through a negative number",
 			in:   sreader(`{"something":  -129 }`),
 			opts: options.Set{
-				Path: "something.else",
+				Paths: []string{"something.else"},
 			},
-			expErr: json.ErrScanNotObject{On: '-'},
+			expErr: json.Classify(json.ErrScanNotObject{On: '-'}, at("something")),
 		},
 
 		{
 			name:   "array that doesn't end - 1",
 			in:     sreader("[1, 2, 3"),
 			exp:    "1\n2\n3\n",
-			expErr: errArrayEOF(2),
+			expErr: errArrayEOF(atIndex("", 2)),
 		},
 
 		{
 			name:   "array that doesn't end - 2",
 			in:     sreader("[1, 2, 3,"),
 			exp:    "1\n2\n3\n",
-			expErr: errArrayEOF(3),
+			expErr: errArrayEOF(atIndex("", 3)),
 		},
 
 		{
 			name:   "array that doesn't end (trailing ws)",
 			in:     sreader("[1, 2, 3,  "),
 			exp:    "1\n2\n3\n",
-			expErr: errArrayEOF(3),
+			expErr: errArrayEOF(atIndex("", 3)),
 		},
 
 		{
 			name:   "array that doesn't end - stutter comma",
 			in:     sreader("[1, 2, 3,,"),
 			exp:    "1\n2\n3\n",
-			expErr: errBadArrayValueStart(',', 3),
+			expErr: errBadArrayValueStart(',', atIndex("", 3)),
 		},
 		{
 			name: "array + \\ns",
@@ -353,7 +735,7 @@ func TestProcessor(t *testing.T) {
 			name:   "object that doesn't close",
 			in:     sreader(`{"x":`),
 			exp:    `{"x":`,
-			expErr: errNonArrayEOF("object"),
+			expErr: errNonArrayEOF("object", at("")),
 		},
 		{
 			name: "multiple strings",
@@ -374,7 +756,7 @@ func TestProcessor(t *testing.T) {
 			name:   "one object followed by garbage",
 			in:     sreader(`{}` + "\n" + `fish`),
 			exp:    "{}\n",
-			expErr: json.ErrBadValue{Value: "fish\000"},
+			expErr: json.Classify(json.ErrBadValue{Value: "fish"}, at("")),
 		},
 		{
 			name:   "array in the stream",
@@ -406,70 +788,1008 @@ func TestProcessor(t *testing.T) {
 		t.Run(tc.name, func(t *testing.T) {
 			out := bytes.NewBuffer(nil)
 			err := processor{
-				tc.in,
-				out,
-				tc.opts,
-				tc.buffered,
+				in:       tc.in,
+				out:      out,
+				options:  tc.opts,
+				buffered: tc.buffered,
 			}.run()
 			assert.Equal(t, tc.exp, out.String(), "expected output")
 			if tc.errChecker != nil {
 				tc.errChecker(t, err)
 			} else {
-				assert.Equal(t, tc.expErr, err, "expected error")
+				assert.Equal(t, tc.expErr, withoutPosition(err), "expected error")
 			}
 		})
 	}
 }
 
-func TestGuessJsonType(t *testing.T) {
+func TestProcessorMultiplePaths(t *testing.T) {
 
 	cases := []struct {
-		in  byte
-		exp string
+		name       string
+		in         string
+		paths      []string
+		exp        string
+		expOutputs map[string]string
+		expErr     error
 	}{
-		{'{', "object"},
-		{'"', "string"},
-		{'5', "number"},
-		{'1', "number"},
-		{'0', "number"},
-		{'9', "number"},
-		{'-', "number"},
-		{'x', ""},
-		{'\'', ""},
-		{'[', "array"},
+		{
+			name:  "combined, in a different order",
+			in:    `{"groups":[{"g":1}],"other":{"users":[0]},"users":[{"u":1},{"u":2}]}`,
+			paths: []string{"users", "groups"},
+			exp: `{"path":"groups","value":{"g":1}}` + "\n" +
+				`{"path":"users","value":{"u":1}}` + "\n" +
+				`{"path":"users","value":{"u":2}}` + "\n",
+		},
+		{
+			name:  "separate outputs",
+			in:    `{"groups":[{"g":1}],"users":[{"u":1},{"u":2}]}`,
+			paths: []string{"users=u", "groups=g"},
+			expOutputs: map[string]string{
+				"u": `{"u":1}` + "\n" + `{"u":2}` + "\n",
+				"g": `{"g":1}` + "\n",
+			},
+		},
+		{
+			name:  "one separate output",
+			in:    `{"groups":[{"g":1}],"users":[{"u":1},{"u":2}]}`,
+			paths: []string{"users", "groups=g"},
+			exp:   `{"u":1}` + "\n" + `{"u":2}` + "\n",
+			expOutputs: map[string]string{
+				"g": `{"g":1}` + "\n",
+			},
+		},
+		{
+			name:  "deeper paths",
+			in:    `{"a":{"x":[1],"y":{"z":[2]}},"b":[[3],[4],[5]]}`,
+			paths: []string{"a.y.z", "b[1:]", "a.x"},
+			exp: `{"path":"a.x","value":1}` + "\n" +
+				`{"path":"a.y.z","value":2}` + "\n" +
+				`{"path":"b[1]","value":4}` + "\n" +
+				`{"path":"b[2]","value":5}` + "\n",
+		},
+		{
+			name:  "wildcards and indexes",
+			in:    `[{"a":1,"b":[2]},{"a":3,"b":[4]}]`,
+			paths: []string{"[*].a", "[1].b"},
+			exp: `{"path":"[0].a","value":1}` + "\n" +
+				`{"path":"[1].a","value":3}` + "\n" +
+				`{"path":"[1].b","value":4}` + "\n",
+		},
+		{
+			name:   "missing path",
+			in:     `{"users":[1]}`,
+			paths:  []string{"users", "groups"},
+			exp:    `{"path":"users","value":1}` + "\n",
+			expErr: errBadPath("groups", at("")),
+		},
+		{
+			name:   "overlapping paths",
+			in:     `{"users":{"x":[1]}}`,
+			paths:  []string{"users", "users.x"},
+			expErr: errOverlappingPaths("users"),
+		},
+		{
+			name:   "unopened output",
+			in:     `{}`,
+			paths:  []string{"users=nowhere"},
+			expErr: errNoOutput("nowhere"),
+		},
 	}
 
 	for _, tc := range cases {
-		name := fmt.Sprintf("%c -> %s", tc.in, tc.exp)
-		t.Run(name, func(t *testing.T) {
-			get := guessJSONType(tc.in)
-			assert.Equal(t, tc.exp, get)
+		t.Run(tc.name, func(t *testing.T) {
+			out := bytes.NewBuffer(nil)
+			buffers := map[string]*bytes.Buffer{}
+			outputs := map[string]io.Writer{}
+			for name := range tc.expOutputs {
+				buffers[name] = bytes.NewBuffer(nil)
+				outputs[name] = buffers[name]
+			}
+
+			err := processor{
+				in:      sreader(tc.in),
+				out:     out,
+				options: options.Set{Paths: tc.paths},
+				outputs: outputs,
+			}.run()
+			assert.Equal(t, tc.expErr, withoutPosition(err), "expected error")
+			assert.Equal(t, tc.exp, out.String(), "expected output")
+			for name, exp := range tc.expOutputs {
+				assert.Equal(t, exp, buffers[name].String(), "output %s", name)
+			}
 		})
 	}
 }
 
-func TestErrPathLeadToBadValueMessage(t *testing.T) {
+func TestProcessorMissing(t *testing.T) {
 
 	cases := []struct {
-		name     string
-		clue     byte
-		contains string
+		name    string
+		in      string
+		opts    options.Set
+		exp     string
+		expWarn string
+		expErr  error
 	}{
 		{
-			name:     "a number tells us it's a number type",
-			clue:     '-',
-			contains: "number",
+			name: "error",
+			in:   `{"a":{"x":[1]}} {"a":{}} {"a":{"x":[3]}}`,
+			opts: options.Set{
+				Paths:   []string{"a.x"},
+				Missing: options.MissingError,
+			},
+			exp:    "1\n",
+			expErr: errBadPath("x", at("a")),
 		},
 		{
-			name:     "non-start character gives us a reasonable clue",
-			clue:     'Z',
-			contains: `Z`,
+			name: "skip",
+			in:   `{"a":{"x":[1]}} {"a":{}} {"a":{"x":[3]}}`,
+			opts: options.Set{
+				Paths:   []string{"a.x"},
+				Missing: options.MissingSkip,
+			},
+			exp:     "1\n3\n",
+			expWarn: "skipped stdin at (a): path node did not exist: x\n",
+		},
+		{
+			name: "skip, under a wildcard",
+			in:   `{"a":[{"x":[1]},{"y":2},{"x":3}]}`,
+			opts: options.Set{
+				Paths:   []string{"a[*].x"},
+				Missing: options.MissingSkip,
+			},
+			exp:     "1\n3\n",
+			expWarn: "skipped stdin at (a[1]): path node did not exist: x\n",
+		},
+		{
+			name: "skip, index",
+			in:   `[[1],[],[2,3]]`,
+			opts: options.Set{
+				Paths:   []string{"[*][1]"},
+				Missing: options.MissingSkip,
+			},
+			exp: "3\n",
+			expWarn: "skipped stdin at ([0]): path node did not exist: [1]\n" +
+				"skipped stdin at ([1]): path node did not exist: [1]\n",
+		},
+		{
+			name: "emit null",
+			in:   `{"a":{"x":[1]}} {"a":{}} {"a":{"x":[3]}}`,
+			opts: options.Set{
+				Paths:   []string{"a.x"},
+				Missing: options.MissingNull,
+			},
+			exp: "1\nnull\n3\n",
+		},
+		{
+			name: "emit null, tagged",
+			in:   `{"a":{}}`,
+			opts: options.Set{
+				Paths:   []string{"a.x"},
+				Missing: options.MissingNull,
+				TagPath: true,
+			},
+			exp: `{"path":"a.x","value":null}` + "\n",
+		},
+		{
+			name: "default",
+			in:   `{"a":{"x":[1]}} {"a":{}}`,
+			opts: options.Set{
+				Paths:          []string{"a.x"},
+				Missing:        options.MissingDefault,
+				MissingDefault: `{"none":true}`,
+			},
+			exp: "1\n" + `{"none":true}` + "\n",
+		},
+		{
+			name: "pointer, skip",
+			in:   `{"a":[1]} {"a":{"b":2}}`,
+			opts: options.Set{
+				Pointer: "/a/b",
+				Missing: options.MissingSkip,
+			},
+			exp:     "2\n",
+			expWarn: "skipped stdin at (/a): path node did not exist: b\n",
+		},
+		{
+			name: "multiple paths, default",
+			in:   `{"a":[1]} {"b":[2]}`,
+			opts: options.Set{
+				Paths:          []string{"a", "b"},
+				Missing:        options.MissingDefault,
+				MissingDefault: "0",
+			},
+			exp: `{"path":"a","value":1}` + "\n" + `{"path":"b","value":0}` + "\n" +
+				`{"path":"b","value":2}` + "\n" + `{"path":"a","value":0}` + "\n",
+		},
+		{
+			name: "multiple paths, skip",
+			in:   `{"a":[1]}`,
+			opts: options.Set{
+				Paths:   []string{"a", "b"},
+				Missing: options.MissingSkip,
+			},
+			exp:     `{"path":"a","value":1}` + "\n",
+			expWarn: "skipped stdin: path node did not exist: b\n",
 		},
 	}
 
 	for _, tc := range cases {
 		t.Run(tc.name, func(t *testing.T) {
-			err := errPathLeadToBadValue(tc.clue, "xyz")
+			out := bytes.NewBuffer(nil)
+			warn := bytes.NewBuffer(nil)
+			err := processor{
+				in:      sreader(tc.in),
+				out:     out,
+				options: tc.opts,
+				warn:    warn,
+			}.run()
+			assert.Equal(t, tc.expErr, withoutPosition(err), "expected error")
+			assert.Equal(t, tc.exp, out.String(), "expected output")
+			assert.Equal(t, tc.expWarn, warn.String(), "expected warnings")
+		})
+	}
+}
+
+func TestProcessorOnErrorSkip(t *testing.T) {
+
+	cases := []struct {
+		name       string
+		in         string
+		opts       options.Set
+		exp        string
+		expWarn    string
+		expRejects string
+		expErr     error
+	}{
+		{
+			name:   "stop",
+			in:     `[1, truex, 2]`,
+			exp:    "1\n",
+			expErr: arrayJSONErr(atIndex("", 1), json.ErrBadValue{Value: "truex"}),
+		},
+		{
+			name: "skip",
+			in:   `[1, truex, 2, 1.e5, 3 4, x, 5]`,
+			opts: options.Set{OnError: options.OnErrorSkip},
+			exp:  "1\n2\n3\n5\n",
+			expWarn: "skipped stdin: array JSON decode error: bad value: truex\n" +
+				"skipped stdin: array JSON decode error: bad number, expected a digit after the decimal point but found 'e' (at character 3 of the number)\n" +
+				"skipped stdin: unexpected character: 4\n" +
+				"skipped stdin: at array index 5 found something which doesn't look like a JSON value, starts with: x\n",
+			expRejects: `{"file":null,"offset":4,"index":1,"path":"","message":"array JSON decode error: bad value: truex","raw":"truex"}` + "\n" +
+				`{"file":null,"offset":14,"index":3,"path":"","message":"array JSON decode error: bad number, expected a digit after the decimal point but found 'e' (at character 3 of the number)","raw":"1.e5"}` + "\n" +
+				`{"file":null,"offset":22,"index":4,"path":"","message":"unexpected character: 4","raw":"4"}` + "\n" +
+				`{"file":null,"offset":25,"index":5,"path":"","message":"at array index 5 found something which doesn't look like a JSON value, starts with: x","raw":"x"}` + "\n",
+		},
+		{
+			name:       "skip, strict",
+			in:         "[{\"a\":1},\n {\"a\" 2, \"b\": [3, 4]},\n {\"a\":5}]",
+			opts:       options.Set{OnError: options.OnErrorSkip, Strict: true},
+			exp:        `{"a":1}` + "\n" + `{"a":5}` + "\n",
+			expWarn:    "skipped stdin: array JSON decode error: invalid JSON: expected ':' but found '2'\n",
+			expRejects: `{"file":null,"offset":11,"index":1,"path":"","message":"array JSON decode error: invalid JSON: expected ':' but found '2'","raw":"{\"a\" 2, \"b\": [3, 4]}"}` + "\n",
+		},
+		{
+			name:       "skip, strict, down a path",
+			in:         `{"a":{"b":[1, [2}, 3]}, "c":4}`,
+			opts:       options.Set{OnError: options.OnErrorSkip, Strict: true, Paths: []string{"a.b"}},
+			exp:        "1\n3\n",
+			expWarn:    "skipped stdin at (a.b): array JSON decode error: invalid JSON: expected ',' or ']' but found '}'\n",
+			expRejects: `{"file":null,"offset":14,"index":1,"path":"a.b","message":"array JSON decode error: invalid JSON: expected ',' or ']' but found '}'","raw":"[2}"}` + "\n",
+		},
+		{
+			name:       "not valid UTF-8",
+			in:         "[1, \"\xff\"x, 2]",
+			opts:       options.Set{OnError: options.OnErrorSkip, Strict: true},
+			exp:        "1\n2\n",
+			expWarn:    "skipped stdin: array JSON decode error: invalid JSON: invalid UTF-8 in string\n",
+			expRejects: `{"file":null,"offset":4,"index":1,"path":"","message":"array JSON decode error: invalid JSON: invalid UTF-8 in string","raw_base64":"Iv8ieA=="}` + "\n",
+		},
+		{
+			name:       "can't skip running out",
+			in:         `[1, truex, [2`,
+			opts:       options.Set{OnError: options.OnErrorSkip},
+			exp:        "1\n",
+			expWarn:    "skipped stdin: array JSON decode error: bad value: truex\n",
+			expRejects: `{"file":null,"offset":4,"index":1,"path":"","message":"array JSON decode error: bad value: truex","raw":"truex"}` + "\n",
+			expErr:     arrayJSONErr(atIndex("", 2), io.EOF),
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := bytes.Buffer{}
+			warn := bytes.Buffer{}
+			rejects := bytes.Buffer{}
+
+			err := processor{
+				in:      sreader(tc.in),
+				out:     &out,
+				warn:    &warn,
+				rejects: &rejects,
+				options: tc.opts,
+			}.run()
+
+			assert.Equal(t, tc.expErr, withoutPosition(err), "expected error")
+			assert.Equal(t, tc.exp, out.String(), "output")
+			assert.Equal(t, tc.expWarn, warn.String(), "warnings")
+			assert.Equal(t, tc.expRejects, rejects.String(), "rejects")
+		})
+	}
+}
+
+func TestProcessorSalvage(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "cut off in a value",
+			in:   `[{"a":1},{"b":2},{"a":`,
+			exp:  `{"a":1}` + "\n" + `{"b":2}` + "\n",
+			expErr: errPartial(2, 2, 17,
+				arrayJSONErr(atIndex("", 2), io.EOF),
+			),
+		},
+		{
+			name: "unwound, counting each record",
+			in:   `[{"id":1,"xs":[1,2]},{"id":2,"xs":[3]},{"id":`,
+			opts: options.Set{Unwind: "xs"},
+			exp:  `{"id":1,"xs":1}` + "\n" + `{"id":1,"xs":2}` + "\n" + `{"id":2,"xs":3}` + "\n",
+			expErr: errPartial(3, 2, 39,
+				arrayJSONErr(atIndex("", 2), io.EOF),
+			),
+		},
+		{
+			name:   "cut off after an object",
+			in:     `[{"a":1}`,
+			exp:    `{"a":1}` + "\n",
+			expErr: errPartial(1, 1, 8, errArrayEOF(atIndex("", 1))),
+		},
+		{
+			name:   "cut off in a number",
+			in:     `[1,2.`,
+			exp:    "1\n",
+			expErr: errPartial(1, 1, 3, arrayJSONErr(atIndex("", 1), io.EOF)),
+		},
+		{
+			name:   "cut off in a literal",
+			in:     `[{"a":1},{"b":2},fal`,
+			exp:    `{"a":1}` + "\n" + `{"b":2}` + "\n",
+			expErr: errPartial(2, 2, 17, arrayJSONErr(atIndex("", 2), io.EOF)),
+		},
+		{
+			name:   "cut off after a comma",
+			in:     `[1, 2,`,
+			exp:    "1\n2\n",
+			expErr: errPartial(2, 2, 6, errArrayEOF(atIndex("", 2))),
+		},
+		{
+			name: "cut off after a value",
+			in:   `[1, 2, 34`,
+			// we can't tell that 34 is all of the number:
+			exp:    "1\n2\n",
+			expErr: errPartial(2, 2, 7, errArrayEOF(atIndex("", 2))),
+		},
+		{
+			name: "strict",
+			in:   `[1, 2, {"a":`,
+			opts: options.Set{Strict: true},
+			exp:  "1\n2\n",
+			expErr: errPartial(2, 2, 7,
+				arrayJSONErr(atIndex("", 2), json.ErrInvalidJSON{Offset: 12, Problem: "unexpected end of input"}),
+			),
+		},
+		{
+			name:   "down a path",
+			in:     `{"x": {"y": [1, [2, 3], [4`,
+			opts:   options.Set{Paths: []string{"x.y"}},
+			exp:    "1\n[2, 3]\n",
+			expErr: errPartial(2, 2, 24, arrayJSONErr(atIndex("x.y", 2), io.EOF)),
+		},
+		{
+			name:   "nothing to salvage",
+			in:     `[`,
+			expErr: errPartial(0, 0, 1, errArrayEOF(atIndex("", 0))),
+		},
+		{
+			name: "not cut off",
+			in:   `[1, 2]`,
+			exp:  "1\n2\n",
+		},
+		{
+			name:   "other errors",
+			in:     `[1, 2 3]`,
+			expErr: errUnexpectedCharacter('3', atIndex("", 1)),
+		},
+	}
+
+	for i := range cases {
+		cases[i].opts.Salvage = true
+		cases[i].buffered = true
+	}
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorTrailing(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "ignored by default",
+			in:   `[1,2]]]garbage`,
+			exp:  "1\n2\n",
+		},
+		{
+			name:   "error",
+			in:     `[1,2]]]garbage`,
+			opts:   options.Set{Trailing: options.TrailingError},
+			exp:    "1\n2\n",
+			expErr: errTrailing(']', at("")),
+		},
+		{
+			name:   "error, another array",
+			in:     `[1][2]`,
+			opts:   options.Set{Trailing: options.TrailingError},
+			exp:    "1\n",
+			expErr: errTrailing('[', at("")),
+		},
+		{
+			name: "error, just whitespace",
+			in:   "[1] \n\t",
+			opts: options.Set{Trailing: options.TrailingError},
+			exp:  "1\n",
+		},
+		{
+			name:   "strict means error",
+			in:     `[1] 2`,
+			opts:   options.Set{Strict: true},
+			exp:    "1\n",
+			expErr: errTrailing('2', at("")),
+		},
+		{
+			name:   "strict, invalid",
+			in:     `[1,2]]]garbage`,
+			opts:   options.Set{Strict: true},
+			exp:    "1\n2\n",
+			expErr: json.Classify(json.ErrInvalidJSON{Offset: 5, Problem: "expected a value but found ']'"}, at("")),
+		},
+		{
+			name: "strict, ignored",
+			in:   `[1] 2`,
+			opts: options.Set{Strict: true, Trailing: options.TrailingIgnore},
+			exp:  "1\n",
+		},
+		{
+			name: "stream",
+			in:   `[1][2, 3] {"a":4} "x"`,
+			opts: options.Set{Trailing: options.TrailingStream},
+			exp:  "1\n2\n3\n" + `{"a":4}` + "\n" + `"x"` + "\n",
+		},
+		{
+			name:   "stream, garbage",
+			in:     `[1] 2 ]`,
+			opts:   options.Set{Trailing: options.TrailingStream},
+			exp:    "1\n2\n",
+			expErr: errBadStreamValue(']', at("")),
+		},
+		{
+			name: "not an array",
+			in:   `{"a":1} {"a":2}`,
+			opts: options.Set{Trailing: options.TrailingError},
+			exp:  `{"a":1}` + "\n" + `{"a":2}` + "\n",
+		},
+	}
+
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorFlattenStream(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "arrays and other values",
+			in:   `[1,2][3]{"a":1}`,
+			exp:  "1\n2\n3\n" + `{"a":1}` + "\n",
+		},
+		{
+			name:     "starting with an object, buffered",
+			in:       "{\"a\":1}\n[1, [2]]\n\"x\"\n[]\n[3]\n",
+			buffered: true,
+			exp:      `{"a":1}` + "\n1\n[2]\n" + `"x"` + "\n3\n",
+		},
+		{
+			name: "tagged",
+			in:   `[1] 2`,
+			opts: options.Set{TagPath: true},
+			exp:  `{"path":"","value":1}` + "\n" + `{"path":"","value":2}` + "\n",
+		},
+		{
+			name:   "expecting arrays",
+			in:     `[1] 2`,
+			opts:   options.Set{ExpectArray: true},
+			exp:    "1\n",
+			expErr: errNotArrayWas("number", at("")),
+		},
+		{
+			name:   "garbage",
+			in:     `[1] x`,
+			exp:    "1\n",
+			expErr: errBadStreamValue('x', at("")),
+		},
+		{
+			name:   "cut off",
+			in:     `[1] {"a":`,
+			exp:    "1\n" + `{"a":`,
+			expErr: errNonArrayEOF("object", at("")),
+		},
+	}
+
+	for i := range cases {
+		cases[i].opts.FlattenStream = true
+	}
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorDepth(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "one level",
+			in:   `[[{"a":1},{"b":2}], [{"c":3}]]`,
+			opts: options.Set{Depth: 1},
+			exp:  `[{"a":1},{"b":2}]` + "\n" + `[{"c":3}]` + "\n",
+		},
+		{
+			name: "two levels",
+			in:   `[[{"a":1},{"b":2}], [{"c":3}]]`,
+			opts: options.Set{Depth: 2},
+			exp:  `{"a":1}` + "\n" + `{"b":2}` + "\n" + `{"c":3}` + "\n",
+		},
+		{
+			name: "deeper than asked",
+			in:   `[[1, [2, 3]], 4]`,
+			opts: options.Set{Depth: 2},
+			exp:  "1\n[2, 3]\n4\n",
+		},
+		{
+			name: "all",
+			in:   `[[1, [2, [3]]], [], [[]], 4]`,
+			opts: options.Set{Depth: options.DepthAll},
+			exp:  "1\n2\n3\n4\n",
+		},
+		{
+			name: "down a path",
+			in:   `{"x": [[1], [2]]}`,
+			opts: options.Set{Depth: 2, Paths: []string{"x"}},
+			exp:  "1\n2\n",
+		},
+		{
+			name: "tagged",
+			in:   `[[1], [2]]`,
+			opts: options.Set{Depth: 2, TagPath: true},
+			exp:  `{"path":"[0]","value":1}` + "\n" + `{"path":"[1]","value":2}` + "\n",
+		},
+		{
+			name:   "error in a nested array",
+			in:     `[[1], [2, x]]`,
+			opts:   options.Set{Depth: 2},
+			exp:    "1\n2\n",
+			expErr: errBadArrayValueStart('x', atIndex("[1]", 1)),
+		},
+		{
+			name: "skipping in a nested array",
+			in:   `[[1, truex, 2], [3]]`,
+			opts: options.Set{Depth: 2, OnError: options.OnErrorSkip},
+			exp:  "1\n2\n3\n",
+		},
+		{
+			name:   "salvaging a nested array",
+			in:     `[[1, 2], [3, 4`,
+			opts:   options.Set{Depth: 2, Salvage: true},
+			exp:    "1\n2\n3\n",
+			expErr: errPartial(3, 1, 13, errArrayEOF(atIndex("[1]", 1))),
+		},
+	}
+
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorEntries(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "entries",
+			in:   `{"u1":{"n":"a"}, "u2": [1, 2], "u3":3}`,
+			exp: `{"key":"u1","value":{"n":"a"}}` + "\n" +
+				`{"key":"u2","value":[1, 2]}` + "\n" +
+				`{"key":"u3","value":3}` + "\n",
+		},
+		{
+			name: "keyed",
+			in:   `{"u1":{"n":"a"}, "u2": { } ,"u3":{ "n" : "c" }}`,
+			opts: options.Set{EntriesKey: "id"},
+			exp: `{"id":"u1","n":"a"}` + "\n" +
+				`{"id":"u2"}` + "\n" +
+				`{"id":"u3","n":"c"}` + "\n",
+		},
+		{
+			name: "keyed, the value has the field already",
+			in:   `{"u1":{"id":5, "n":"a"}}`,
+			opts: options.Set{EntriesKey: "id"},
+			exp:  `{"id":"u1","n":"a"}` + "\n",
+		},
+		{
+			name: "keys with escapes, as they were",
+			in:   `{"a\"b":1, "\u00e9":2}`,
+			exp:  `{"key":"a\"b","value":1}` + "\n" + `{"key":"\u00e9","value":2}` + "\n",
+		},
+		{
+			name: "keys as they were, not made HTML safe or valid UTF-8",
+			in:   "{\"a<b&c\":{\"x>\":1}, \"\xff\":2}",
+			exp:  `{"key":"a<b&c","value":{"x>":1}}` + "\n" + "{\"key\":\"\xff\",\"value\":2}\n",
+		},
+		{
+			name: "keyed, keys as they were",
+			in:   "{\"a<b\":{\"x>\":1, \"\xff\":2}}",
+			opts: options.Set{EntriesKey: "<id>"},
+			exp:  "{\"<id>\":\"a<b\",\"x>\":1,\"\xff\":2}\n",
+		},
+		{
+			name: "empty",
+			in:   `{}`,
+			exp:  "",
+		},
+		{
+			name: "down a path",
+			in:   `{"users":{"u1":{"n":"a"}}}`,
+			opts: options.Set{Paths: []string{"users"}, EntriesKey: "id"},
+			exp:  `{"id":"u1","n":"a"}` + "\n",
+		},
+		{
+			name: "stream",
+			in:   "{\"a\":1}\n{\"b\":2}\n",
+			exp:  `{"key":"a","value":1}` + "\n" + `{"key":"b","value":2}` + "\n",
+		},
+		{
+			name: "arrays are unpacked as usual",
+			in:   `[{"a":1}]`,
+			exp:  `{"a":1}` + "\n",
+		},
+		{
+			name: "tagged",
+			in:   `{"x":{"a":1}}`,
+			opts: options.Set{Paths: []string{"x"}, TagPath: true},
+			exp:  `{"path":"x","value":{"key":"a","value":1}}` + "\n",
+		},
+		{
+			name: "strict",
+			in:   `{"u1":{"n":1}, "u2":{"n":2}}`,
+			opts: options.Set{EntriesKey: "id", Strict: true},
+			exp:  `{"id":"u1","n":1}` + "\n" + `{"id":"u2","n":2}` + "\n",
+		},
+		{
+			name:   "keyed value not an object",
+			in:     `{"u1":{"n":1}, "u2":2}`,
+			opts:   options.Set{EntriesKey: "id"},
+			exp:    `{"id":"u1","n":1}` + "\n",
+			expErr: errEntryNotObject("number", at("u2")),
+		},
+		{
+			name:   "bad value",
+			in:     `{"u1":x}`,
+			expErr: errBadEntryValue('x', at("u1")),
+		},
+		{
+			name:   "cut off",
+			in:     `{"u1":1, "u2":{"n"`,
+			exp:    `{"key":"u1","value":1}` + "\n" + `{"key":"u2","value":{"n"`,
+			expErr: errNonArrayEOF("object", at("u2")),
+		},
+	}
+
+	for i := range cases {
+		cases[i].opts.Entries = true
+	}
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorUnwind(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "array of objects",
+			in:   `[{"id":1,"items":[{"sku":"a"},{"sku":"b","id":9}]},{"id":2,"items":[]}]`,
+			exp:  `{"id":1,"sku":"a"}` + "\n" + `{"sku":"b","id":9}` + "\n",
+		},
+		{
+			name: "stream of objects",
+			in:   "{\"id\":1,\"items\":[1, 2]}\n{\"id\":2,\"items\":\"x\"}\n{\"id\":3}\n{\"items\":null}",
+			exp:  `{"id":1,"items":1}` + "\n" + `{"id":1,"items":2}` + "\n" + `{"id":2,"items":"x"}` + "\n",
+		},
+		{
+			name: "nested parent",
+			in:   `{"id":1, "n":"x", "items":[{"sku":"a"}, 2]}`,
+			opts: options.Set{UnwindParent: "order"},
+			exp:  `{"order":{"id":1,"n":"x"},"sku":"a"}` + "\n" + `{"order":{"id":1,"n":"x"},"items":2}` + "\n",
+		},
+		{
+			name: "nothing else",
+			in:   `{"items":[{}]}`,
+			opts: options.Set{UnwindParent: "order"},
+			exp:  `{"order":{}}` + "\n",
+		},
+		{
+			name: "keys as they were",
+			in:   "{\"id\":1,\"a<b\":2,\"\\u0069tems\":[{\"x>\":1}, 3],\"\xff\":4}",
+			exp: "{\"id\":1,\"a<b\":2,\"\xff\":4,\"x>\":1}\n" +
+				"{\"id\":1,\"a<b\":2,\"\xff\":4,\"\\u0069tems\":3}\n",
+		},
+		{
+			name: "parent field of our own, not made HTML safe",
+			in:   `{"a<b":1,"items":[2]}`,
+			opts: options.Set{UnwindParent: "<order>"},
+			exp:  `{"<order>":{"a<b":1},"items":2}` + "\n",
+		},
+		{
+			name: "down a path",
+			in:   `{"orders":[{"id":1,"items":[{"sku":"a"}]}]}`,
+			opts: options.Set{Paths: []string{"orders"}},
+			exp:  `{"id":1,"sku":"a"}` + "\n",
+		},
+		{
+			name: "tagged",
+			in:   `{"orders":[{"id":1,"items":[2]}]}`,
+			opts: options.Set{Paths: []string{"orders"}, TagPath: true},
+			exp:  `{"path":"orders","value":{"id":1,"items":2}}` + "\n",
+		},
+		{
+			name: "strict",
+			in:   `[{"id":1,"items":[2, 3]}]`,
+			opts: options.Set{Strict: true},
+			exp:  `{"id":1,"items":2}` + "\n" + `{"id":1,"items":3}` + "\n",
+		},
+		{
+			name:   "not an object",
+			in:     `[{"id":1,"items":[2]}, 3]`,
+			exp:    `{"id":1,"items":2}` + "\n",
+			expErr: errUnwindNotObject("number", atIndex("", 1)),
+		},
+		{
+			name:   "cut off",
+			in:     `[{"id":1,"items":[2`,
+			expErr: arrayJSONErr(atIndex("", 0), io.EOF),
+		},
+	}
+
+	for i := range cases {
+		cases[i].opts.Unwind = "items"
+	}
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorContext(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "context",
+			in:   `{"meta":{"source":"x", "ts":1}, "data":{"items":[{"id":1}, 2, {}]}}`,
+			opts: options.Set{Paths: []string{"data.items"}, Contexts: []string{"meta=m"}},
+			exp: `{"m":{"source":"x", "ts":1},"id":1}` + "\n" +
+				`{"m":{"source":"x", "ts":1},"value":2}` + "\n" +
+				`{"m":{"source":"x", "ts":1}}` + "\n",
+		},
+		{
+			name: "several, at different levels",
+			in:   `{"meta":{"source":"x"}, "data":{"page":3, "items":[{"id":1}]}}`,
+			opts: options.Set{Paths: []string{"data.items"}, Contexts: []string{"meta.source=src", "data.page"}},
+			exp:  `{"src":"x","data.page":3,"id":1}` + "\n",
+		},
+		{
+			name: "stream",
+			in:   "{\"meta\":1, \"data\":[{\"a\":1}]}\n{\"meta\":2, \"data\":[{\"a\":2}]}\n",
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}},
+			exp:  `{"meta":1,"a":1}` + "\n" + `{"meta":2,"a":2}` + "\n",
+		},
+		{
+			name: "keys as they were",
+			in:   `{"meta":1, "data":[{"x>":1}]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta=<m>"}},
+			exp:  `{"<m>":1,"x>":1}` + "\n",
+		},
+		{
+			name: "tagged",
+			in:   `{"meta":1, "data":[{"a":1}]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}, TagPath: true},
+			exp:  `{"path":"data","value":{"meta":1,"a":1}}` + "\n",
+		},
+		{
+			name:   "after the records",
+			in:     `{"data":[1], "meta":1}`,
+			opts:   options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}},
+			exp:    "",
+			expErr: errContextNotFound("meta", atIndex("data", 0)),
+		},
+		{
+			name:   "after the records, skipped",
+			in:     `{"data":[1], "meta":1}`,
+			opts:   options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}, Missing: options.MissingSkip},
+			exp:    "1\n",
+			expErr: errLateContext("meta", at("meta")),
+		},
+		{
+			name: "after no records",
+			in:   `{"data":[], "meta":1}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}},
+			exp:  "",
+		},
+		{
+			name:   "missing, nothing written",
+			in:     `{"data":{"items":[{"id":1},2]}}`,
+			opts:   options.Set{Paths: []string{"data.items"}, Contexts: []string{"meta=meta"}},
+			exp:    "",
+			expErr: errContextNotFound("meta", atIndex("data.items", 0)),
+		},
+		{
+			name:   "missing, a record that isn't in an array",
+			in:     `{"data":{"id":1}}`,
+			opts:   options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}},
+			exp:    "",
+			expErr: errContextNotFound("meta", at("data")),
+		},
+		{
+			name:   "missing, no records",
+			in:     `{"data":[]}`,
+			opts:   options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}},
+			exp:    "",
+			expErr: errBadPath("meta", at("")),
+		},
+		{
+			name: "missing, skipped",
+			in:   `{"data":[1]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}, Missing: options.MissingSkip},
+			exp:  "1\n",
+		},
+		{
+			name: "the record has a field of the same name",
+			in:   `{"meta":1, "data":[{"meta":2, "id":1}]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}},
+			exp:  `{"meta":1,"id":1}` + "\n",
+		},
+		{
+			name: "missing, null, found out after the records",
+			in:   `{"data":[1]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}, Missing: options.MissingNull},
+			exp:  `{"meta":null,"value":1}` + "\n",
+		},
+		{
+			name: "missing, default",
+			in:   `{"data":[{"id":1}]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta"}, Missing: options.MissingDefault, MissingDefault: `"none"`},
+			exp:  `{"meta":"none","id":1}` + "\n",
+		},
+		{
+			name: "missing, null",
+			in:   `{"meta":{}, "data":[1]}`,
+			opts: options.Set{Paths: []string{"data"}, Contexts: []string{"meta.source=src"}, Missing: options.MissingNull},
+			exp:  `{"src":null,"value":1}` + "\n",
+		},
+	}
+
+	runProcessorCases(t, cases)
+}
+
+func TestProcessorDuplicateKeys(t *testing.T) {
+
+	cases := []processorCase{
+		{
+			name: "first by default",
+			in:   `{"a":{"x":[1], "x":[2]}}`,
+			opts: options.Set{Paths: []string{"a.x"}},
+			exp:  "1\n",
+		},
+		{
+			name: "last",
+			in:   `{"a":{"x":[1], "y":0, "x":[2,3]}, "a":{"x":[4]}}`,
+			opts: options.Set{
+				Paths:         []string{"a.x"},
+				DuplicateKeys: options.DuplicateLast,
+			},
+			exp: "4\n",
+		},
+		{
+			name: "error",
+			in:   `{"a":{"x":[1], "x":[2]}}`,
+			opts: options.Set{
+				Paths:         []string{"a.x"},
+				DuplicateKeys: options.DuplicateError,
+			},
+			exp:    "1\n",
+			expErr: errDuplicateKey(at("a.x")),
+		},
+		{
+			name: "error, nested keys aren't duplicates",
+			in:   `{"a":{"x":[1], "y":{"x":2}}}`,
+			opts: options.Set{
+				Paths:         []string{"a.x"},
+				DuplicateKeys: options.DuplicateError,
+			},
+			exp: "1\n",
+		},
+		{
+			name: "all",
+			in:   `{"a":{"x":[1], "y":{"x":0}, "x":[2,3]}, "a":{"x":[4]}} {"a":{"x":5}}`,
+			opts: options.Set{
+				Paths:         []string{"a.x"},
+				DuplicateKeys: options.DuplicateAll,
+			},
+			exp: "1\n2\n3\n4\n5\n",
+		},
+		{
+			name: "pointer, last",
+			in:   `{"a":1, "a":2}`,
+			opts: options.Set{
+				Pointer:       "/a",
+				DuplicateKeys: options.DuplicateLast,
+			},
+			exp: "2\n",
+		},
+		{
+			name: "multiple paths, last",
+			in:   `{"a":{"a":1}, "a":{"a":2}}`,
+			opts: options.Set{
+				Paths:         []string{"a", "*.a"},
+				DuplicateKeys: options.DuplicateLast,
+			},
+			exp: `{"path":"a.a","value":1}` + "\n" + `{"path":"a.a","value":2}` + "\n" +
+				`{"path":"a","value":{"a":2}}` + "\n",
+		},
+		{
+			name: "multiple paths, error",
+			in:   `{"a":[1], "b":[2], "a":[3]}`,
+			opts: options.Set{
+				Paths:         []string{"a", "b"},
+				DuplicateKeys: options.DuplicateError,
+			},
+			exp:    `{"path":"a","value":1}` + "\n" + `{"path":"b","value":2}` + "\n",
+			expErr: errDuplicateKey(at("a")),
+		},
+		{
+			name: "multiple paths, all",
+			in:   `{"a":[1], "b":[2], "a":[3]}`,
+			opts: options.Set{
+				Paths:         []string{"a", "b"},
+				DuplicateKeys: options.DuplicateAll,
+			},
+			exp: `{"path":"a","value":1}` + "\n" + `{"path":"b","value":2}` + "\n" +
+				`{"path":"a","value":3}` + "\n",
+		},
+	}
+
+	runProcessorCases(t, cases)
+}
+
+func TestErrPathLeadToBadValueMessage(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		clue     byte
+		contains string
+	}{
+		{
+			name:     "a number tells us it's a number type",
+			clue:     '-',
+			contains: "number",
+		},
+		{
+			name:     "non-start character gives us a reasonable clue",
+			clue:     'Z',
+			contains: `Z`,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := errPathLeadToBadValue(tc.clue, at("xyz"))
 			str := err.Error()
 			assert.Contains(t, str, tc.contains)
 		})
@@ -479,3 +1799,209 @@ func TestErrPathLeadToBadValueMessage(t *testing.T) {
 func sreader(s string) io.Reader {
 	return strings.NewReader(s)
 }
+
+func TestProcessorErrorPosition(t *testing.T) {
+
+	cases := []struct {
+		name string
+		in   string
+		opts options.Set
+		exp  json.Position
+		// expSnippet is where the caret should be
+		expSnippet json.Snippet
+	}{
+		{
+			name:       "bad array value",
+			in:         "[1,\n2,\n x]",
+			exp:        json.Position{Offset: 8, Line: 3, Column: 2},
+			expSnippet: json.Snippet{Text: " x]", Marker: 1},
+		},
+		{
+			name:       "bad number",
+			in:         "[1,\n  1.e5]",
+			exp:        json.Position{Offset: 8, Line: 2, Column: 5},
+			expSnippet: json.Snippet{Text: "  1.e5]", Marker: 4},
+		},
+		{
+			name:       "ran out",
+			in:         "[1,\n{\"a\":1",
+			exp:        json.Position{Offset: 10, Line: 2, Column: 7},
+			expSnippet: json.Snippet{Text: `{"a":1`, Marker: 6},
+		},
+		{
+			name: "strict",
+			in:   `{"a":[1, {"b":2}, {"c" 3}]}`,
+			opts: options.Set{
+				Strict: true,
+				Paths:  []string{"a"},
+			},
+			exp:        json.Position{Offset: 23, Line: 1, Column: 24},
+			expSnippet: json.Snippet{Text: `{"a":[1, {"b":2}, {"c" 3}]}`, Marker: 23},
+		},
+		{
+			name: "path",
+			in:   "{\"a\":{\"b\":1}}\n{\"a\":[]}",
+			opts: options.Set{
+				Paths: []string{"a.b"},
+			},
+			exp:        json.Position{Offset: 19, Line: 2, Column: 6},
+			expSnippet: json.Snippet{Text: `{"a":[]}`, Marker: 5},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := processor{
+				in:      sreader(tc.in),
+				out:     io.Discard,
+				options: tc.opts,
+			}.run()
+
+			var at json.ErrAt
+			if assert.True(t, errors.As(err, &at), "has a position") {
+				assert.Equal(t, tc.exp, at.Position)
+				assert.Equal(t, tc.expSnippet, at.Snippet)
+			}
+		})
+	}
+}
+
+func TestProcessorErrorTypes(t *testing.T) {
+
+	cases := []struct {
+		name  string
+		in    string
+		opts  options.Set
+		check func(*testing.T, error)
+	}{
+		{
+			name: "syntax",
+			in:   `[1, 2, 1.e5]`,
+			check: func(t *testing.T, err error) {
+				var syntax *json.SyntaxError
+				if assert.True(t, errors.As(err, &syntax)) {
+					assert.Equal(t, json.Where{Offset: 9, Index: 2}, syntax.Where)
+				}
+				var number json.ErrBadNumber
+				assert.True(t, errors.As(err, &number), "still has the detail")
+			},
+		},
+		{
+			name: "ran out",
+			in:   `{"a":[1, 2`,
+			opts: options.Set{Paths: []string{"a"}},
+			check: func(t *testing.T, err error) {
+				assert.True(t, errors.Is(err, io.ErrUnexpectedEOF))
+				var eof *json.UnexpectedEOFError
+				if assert.True(t, errors.As(err, &eof)) {
+					assert.Equal(t, json.Where{Offset: 10, Index: 1, Path: "a"}, eof.Where)
+				}
+			},
+		},
+		{
+			name: "path not found",
+			in:   `{"a":{"b":1}}`,
+			opts: options.Set{Paths: []string{"a.c"}},
+			check: func(t *testing.T, err error) {
+				var notFound *json.PathNotFoundError
+				if assert.True(t, errors.As(err, &notFound)) {
+					assert.Equal(t, "c", notFound.Node)
+					assert.Equal(t, json.Where{Offset: 12, Index: -1, Path: "a"}, notFound.Where)
+				}
+			},
+		},
+		{
+			name: "type mismatch",
+			in:   `{"a":[{"b":"x"}]}`,
+			opts: options.Set{Paths: []string{"a[0].b.c"}},
+			check: func(t *testing.T, err error) {
+				var mismatch *json.TypeMismatchError
+				if assert.True(t, errors.As(err, &mismatch)) {
+					assert.Equal(t, "object", mismatch.Expected)
+					assert.Equal(t, "string", mismatch.Found)
+					assert.Equal(t, json.Where{Offset: 11, Index: -1, Path: "a[0].b"}, mismatch.Where)
+				}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := processor{
+				in:      sreader(tc.in),
+				out:     io.Discard,
+				options: tc.opts,
+			}.run()
+
+			tc.check(t, err)
+		})
+	}
+}
+
+// withoutPosition takes off where an error happened, for the tests that only
+// care what it was.
+// processorCase is a run of the processor over in, for the tables that only
+// check the output and the error.
+type processorCase struct {
+	name     string
+	in       string
+	opts     options.Set
+	buffered bool
+	exp      string
+	expErr   error
+}
+
+func runProcessorCases(t *testing.T, cases []processorCase) {
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			out := bytes.Buffer{}
+
+			err := processor{
+				in:       sreader(tc.in),
+				out:      &out,
+				warn:     io.Discard,
+				buffered: tc.buffered,
+				options:  tc.opts,
+			}.run()
+
+			assert.Equal(t, tc.expErr, withoutPosition(err), "expected error")
+			assert.Equal(t, tc.exp, out.String(), "output")
+		})
+	}
+}
+
+func withoutPosition(err error) error {
+	if at, ok := err.(json.ErrAt); ok {
+		err = at.Err
+	}
+
+	var (
+		syntax   *json.SyntaxError
+		eof      *json.UnexpectedEOFError
+		notFound *json.PathNotFoundError
+		mismatch *json.TypeMismatchError
+		order    *json.OrderError
+	)
+	switch {
+	case errors.As(err, &syntax):
+		syntax.Offset = 0
+	case errors.As(err, &eof):
+		eof.Offset = 0
+	case errors.As(err, &notFound):
+		notFound.Offset = 0
+	case errors.As(err, &mismatch):
+		mismatch.Offset = 0
+	case errors.As(err, &order):
+		order.Offset = 0
+	}
+	return err
+}
+
+// at is where we expect an error outside of an array.
+func at(path string) json.Where {
+	return json.Where{Index: -1, Path: path}
+}
+
+func atIndex(path string, i int) json.Where {
+	return json.Where{Index: i, Path: path}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	stdjson "encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+
+	"github.com/draxil/json2nd/internal/json"
+	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
+)
+
+// exit statuses, see the table in the Readme.
+const (
+	exitOK      = 0
+	exitError   = 1
+	exitOptions = 2
+	exitIO      = 3
+	exitSyntax  = 4
+	exitPath    = 5
+	exitPartial = 6
+)
+
+// kinds of error, as given in -error-format=json reports.
+const (
+	kindError         = "error"
+	kindOptions       = "options"
+	kindIO            = "io"
+	kindSyntax        = "syntax"
+	kindUnexpectedEOF = "unexpected-eof"
+	kindPathNotFound  = "path-not-found"
+	kindTypeMismatch  = "type-mismatch"
+	kindPartial       = "partial"
+	kindOutOfOrder    = "out-of-order"
+)
+
+// errorKind works out what sort of error e is, and what we should exit with
+// because of it.
+func errorKind(e error) (string, int) {
+	var (
+		syntax   *json.SyntaxError
+		eof      *json.UnexpectedEOFError
+		notFound *json.PathNotFoundError
+		mismatch *json.TypeMismatchError
+		order    *json.OrderError
+		opt      optionError
+		partial  partialErr
+		pathErr  path.SyntaxError
+		fsErr    *fs.PathError
+	)
+
+	switch {
+	case errors.As(e, &partial):
+		return kindPartial, exitPartial
+	case errors.As(e, &opt), errors.As(e, &pathErr):
+		return kindOptions, exitOptions
+	case errors.As(e, &syntax):
+		return kindSyntax, exitSyntax
+	case errors.As(e, &eof):
+		return kindUnexpectedEOF, exitSyntax
+	case errors.As(e, &notFound):
+		return kindPathNotFound, exitPath
+	case errors.As(e, &mismatch):
+		return kindTypeMismatch, exitPath
+	case errors.As(e, &order):
+		return kindOutOfOrder, exitPath
+	case errors.As(e, &fsErr):
+		return kindIO, exitIO
+	}
+	return kindError, exitError
+}
+
+// errorReport is an error as we give it with -error-format=json. Anything we
+// don't know is null.
+type errorReport struct {
+	Kind    string  `json:"kind"`
+	File    *string `json:"file"`
+	Offset  *int64  `json:"offset"`
+	Index   *int    `json:"index"`
+	Path    *string `json:"path"`
+	Message string  `json:"message"`
+	// Skipped is for things we skipped over rather than stopped at, e.g
+	// with -missing=skip
+	Skipped bool `json:"skipped,omitempty"`
+}
+
+func newErrorReport(file string, e error) errorReport {
+	kind, _ := errorKind(e)
+	r := errorReport{Kind: kind, Message: e.Error()}
+
+	var fe fileErr
+	if errors.As(e, &fe) {
+		file = fe.name
+	}
+	if file != "" {
+		r.File = &file
+	}
+
+	var at json.ErrAt
+	if errors.As(e, &at) {
+		r.Offset = &at.Offset
+	}
+
+	var skipped skippedErr
+	r.Skipped = errors.As(e, &skipped)
+
+	if w, ok := json.WhereOf(e); ok {
+		if w.Index >= 0 {
+			r.Index = &w.Index
+		}
+		r.Path = &w.Path
+	}
+
+	return r
+}
+
+// report writes out e in the format we've been asked for.
+func report(w io.Writer, format string, file string, e error) error {
+	if format != options.ErrorFormatJSON {
+		_, err := fmt.Fprintln(w, e)
+		if err != nil {
+			return err
+		}
+
+		// show where it went wrong, if we know:
+		var at json.ErrAt
+		if errors.As(e, &at) {
+			_, err = fmt.Fprint(w, at.Snippet)
+		}
+		return err
+	}
+
+	b, err := stdjson.Marshal(newErrorReport(file, e))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", b)
+	return err
+}
+
+// optionError is a problem with the options we were given, rather than with
+// the input.
+type optionError struct {
+	err error
+}
+
+func (e optionError) Error() string {
+	return e.err.Error()
+}
+
+func (e optionError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/draxil/json2nd/internal/options"
+	"github.com/draxil/json2nd/internal/path"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorKind(t *testing.T) {
+	_, openErr := os.Open("./fictional.does.not.exist")
+
+	cases := []struct {
+		name    string
+		in      error
+		expKind string
+		expCode int
+	}{
+		{
+			name:    "something else",
+			in:      errNilInput(),
+			expKind: kindError,
+			expCode: exitError,
+		},
+		{
+			name:    "options",
+			in:      optionError{fmt.Errorf("options conflict")},
+			expKind: kindOptions,
+			expCode: exitOptions,
+		},
+		{
+			name:    "bad path",
+			in:      path.SyntaxError{Path: ".", Column: 1, Err: path.ErrBlankNode},
+			expKind: kindOptions,
+			expCode: exitOptions,
+		},
+		{
+			name:    "can't open",
+			in:      fileOpenErr("x", openErr),
+			expKind: kindIO,
+			expCode: exitIO,
+		},
+		{
+			name:    "syntax",
+			in:      fileProcessErr("x", errBadArrayValueStart('x', atIndex("", 2))),
+			expKind: kindSyntax,
+			expCode: exitSyntax,
+		},
+		{
+			name:    "ran out",
+			in:      errArrayEOF(atIndex("", 2)),
+			expKind: kindUnexpectedEOF,
+			expCode: exitSyntax,
+		},
+		{
+			name:    "path not found",
+			in:      errBadPath("x", at("a")),
+			expKind: kindPathNotFound,
+			expCode: exitPath,
+		},
+		{
+			name:    "partial",
+			in:      fileProcessErr("x", errPartial(2, 2, 17, errArrayEOF(atIndex("", 2)))),
+			expKind: kindPartial,
+			expCode: exitPartial,
+		},
+		{
+			name:    "type mismatch",
+			in:      errNotArrayWas("object", at("")),
+			expKind: kindTypeMismatch,
+			expCode: exitPath,
+		},
+		{
+			name:    "out of order",
+			in:      errLateContext("meta", at("meta")),
+			expKind: kindOutOfOrder,
+			expCode: exitPath,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, code := errorKind(tc.in)
+			assert.Equal(t, tc.expKind, kind, "kind")
+			assert.Equal(t, tc.expCode, code, "exit code")
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+
+	cases := []struct {
+		name string
+		in   string
+		opts options.Set
+		file string
+		exp  string
+	}{
+		{
+			name: "text",
+			in:   "[1,\n x]",
+			file: "in.json",
+			exp: "could not process in.json: at array index 1 found something which doesn't look like a JSON value, starts with: x, at line 2, column 2 (byte offset 5)\n" +
+				" x]\n" +
+				" ^\n",
+		},
+		{
+			name: "json",
+			in:   "[1,\n x]",
+			opts: options.Set{ErrorFormat: options.ErrorFormatJSON},
+			file: "in.json",
+			exp: `{"kind":"syntax","file":"in.json","offset":5,"index":1,"path":"",` +
+				`"message":"could not process in.json: at array index 1 found something which doesn't look like a JSON value, starts with: x, at line 2, column 2 (byte offset 5)"}` + "\n",
+		},
+		{
+			name: "json from stdin",
+			in:   `{"a":{"b":1}}`,
+			opts: options.Set{ErrorFormat: options.ErrorFormatJSON, Paths: []string{"a.c"}},
+			exp: `{"kind":"path-not-found","file":null,"offset":12,"index":null,"path":"a",` +
+				`"message":"path node did not exist: c, at line 1, column 13 (byte offset 12)"}` + "\n",
+		},
+		{
+			name: "json without a position",
+			in:   `{}`,
+			opts: options.Set{ErrorFormat: options.ErrorFormatJSON, Paths: []string{"a..b"}},
+			exp: `{"kind":"options","file":null,"offset":null,"index":null,"path":null,` +
+				`"message":"bad path (a..b) at column 3: blank path node, did you have a double dot?"}` + "\n",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := processor{
+				in:      sreader(tc.in),
+				out:     io.Discard,
+				options: tc.opts,
+			}.run()
+			if tc.file != "" {
+				err = fileProcessErr(tc.file, err)
+			}
+
+			b := bytes.Buffer{}
+			assert.NoError(t, report(&b, tc.opts.ErrorFormat, "", err))
+			assert.Equal(t, tc.exp, b.String())
+		})
+	}
+}
+
+func TestReportOptionConflict(t *testing.T) {
+	oh, err := options.New([]string{"-error-format=json", "-preserve-array", "-flatten-stream"})
+	assert.Error(t, err)
+
+	b := bytes.Buffer{}
+	assert.NoError(t, report(&b, oh.Options.ErrorFormat, "", optionError{err}))
+	assert.Equal(
+		t,
+		`{"kind":"options","file":null,"offset":null,"index":null,"path":null,`+
+			`"message":"options conflict, -preserve-array does not work alongside -flatten-stream"}`+"\n",
+		b.String(),
+	)
+}
+
+func TestReportBadFlagBeforeErrorFormat(t *testing.T) {
+	oh, err := options.New([]string{"-depth", "x", "-error-format=json"})
+	assert.Error(t, err)
+
+	b := bytes.Buffer{}
+	assert.NoError(t, report(&b, oh.Options.ErrorFormat, "", optionError{err}))
+	assert.Equal(
+		t,
+		`{"kind":"options","file":null,"offset":null,"index":null,"path":null,`+
+			`"message":"invalid value \"x\" for flag -depth: should be a number of levels from 1 up, or all"}`+"\n",
+		b.String(),
+	)
+}
+
+func TestReportSkipped(t *testing.T) {
+	warn := bytes.NewBuffer(nil)
+	opts := options.Set{
+		Paths:       []string{"x"},
+		Missing:     options.MissingSkip,
+		ErrorFormat: options.ErrorFormatJSON,
+	}
+
+	err := filemode([]string{"./testdata/other.json"}, processor{out: io.Discard, options: opts, warn: warn})
+	assert.NoError(t, err)
+	assert.Equal(
+		t,
+		`{"kind":"path-not-found","file":"./testdata/other.json","offset":null,"index":null,"path":"",`+
+			`"message":"skipped ./testdata/other.json: path node did not exist: x","skipped":true}`+"\n",
+		warn.String(),
+	)
+}
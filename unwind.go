@@ -0,0 +1,212 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/draxil/json2nd/internal/json"
+	"github.com/draxil/json2nd/internal/options"
+)
+
+// writeUnwound writes out a record for each value in the -unwind field of the
+// object under the cursor, along with the object's other fields. This is the
+// one place we hold on to a record, as we don't know where the field is
+// until we've been through all of it. Objects without the field (or where
+// it's null or empty) don't give us anything, and if it's not an array it's
+// taken to be the one value. It gives back how many records it wrote.
+func (p processor) writeUnwound(out io.Writer, js *json.JSON, w json.Where) (int, error) {
+	clue := js.Peek()
+	if clue != '{' {
+		return 0, errUnwindNotObject(json.TypeOf(clue), w)
+	}
+
+	record := bytes.Buffer{}
+	_, err := js.WriteCurrentTo(&record, true)
+	if err != nil {
+		return 0, err
+	}
+
+	parent, err := objectMembers(record.Bytes())
+	if err != nil {
+		return 0, err
+	}
+
+	var field member
+	for i, m := range parent {
+		if m.key == p.options.Unwind {
+			field = m
+			parent = append(parent[:i:i], parent[i+1:]...)
+			break
+		}
+	}
+
+	values := field.value
+	if len(values) == 0 || values[0] == 'n' {
+		return 0, nil
+	}
+	if values[0] != '[' {
+		return 1, p.writeUnwoundValue(out, parent, field)
+	}
+
+	vs := json.New(bytes.NewReader(values))
+	it, err := vs.IterArray()
+	if err != nil {
+		return 0, err
+	}
+	for n := 0; ; n++ {
+		more, err := it.Next()
+		if err != nil || !more {
+			return n, err
+		}
+
+		value := bytes.Buffer{}
+		_, err = vs.WriteCurrentTo(&value, true)
+		if err != nil {
+			return n, err
+		}
+
+		field.value = value.Bytes()
+		err = p.writeUnwoundValue(out, parent, field)
+		if err != nil {
+			return n, err
+		}
+	}
+}
+
+// writeUnwoundValue writes out the record for one value of the -unwind
+// field, field is the field with just that value. If the value's an object
+// its fields go in the record, taking the place of any of the parent's with
+// the same name, otherwise it keeps the name of the field.
+func (p processor) writeUnwoundValue(out io.Writer, parent []member, field member) error {
+	fields := []member{field}
+	if field.value[0] == '{' {
+		var err error
+		fields, err = objectMembers(field.value)
+		if err != nil {
+			return err
+		}
+	}
+
+	record := &memberWriter{}
+	if p.options.UnwindParent == "" {
+		for _, m := range parent {
+			if !hasMember(fields, m.key) {
+				record.write(m)
+			}
+		}
+	} else {
+		nested := &memberWriter{}
+		for _, m := range parent {
+			nested.write(m)
+		}
+		record.write(member{key: p.options.UnwindParent, value: nested.close()})
+	}
+	for _, m := range fields {
+		record.write(m)
+	}
+	if record.err != nil {
+		return record.err
+	}
+
+	err := p.openTag(out)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write(record.close())
+	if err != nil {
+		return err
+	}
+	err = p.closeTag(out)
+	if err != nil {
+		return err
+	}
+	_, err = out.Write([]byte("\n"))
+	return err
+}
+
+// member is a key of an object and its value as JSON. rawKey is the key as
+// it was in the input, nil for one of our own.
+type member struct {
+	key    string
+	rawKey []byte
+	value  []byte
+}
+
+// objectMembers picks the members out of an object we're holding on to.
+func objectMembers(object []byte) ([]member, error) {
+	js := json.New(bytes.NewReader(object))
+	it, err := js.IterObject()
+	if err != nil {
+		return nil, err
+	}
+
+	var members []member
+	for {
+		more, err := it.Next()
+		if err != nil || !more {
+			return members, err
+		}
+
+		value := bytes.Buffer{}
+		_, err = js.WriteCurrentTo(&value, true)
+		if err != nil {
+			return nil, err
+		}
+		rawKey := append([]byte(nil), it.RawKey()...)
+		members = append(members, member{string(it.Key()), rawKey, value.Bytes()})
+	}
+}
+
+func hasMember(members []member, key string) bool {
+	for _, m := range members {
+		if m.key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// memberWriter puts an object back together from its members.
+type memberWriter struct {
+	b   bytes.Buffer
+	err error
+}
+
+func (w *memberWriter) write(m member) {
+	if w.b.Len() == 0 {
+		w.b.WriteByte('{')
+	} else {
+		w.b.WriteByte(',')
+	}
+
+	key := m.rawKey
+	if key == nil {
+		var err error
+		key, err = marshalKey(m.key)
+		if err != nil && w.err == nil {
+			w.err = err
+		}
+	}
+	w.b.Write(key)
+	w.b.WriteByte(':')
+	w.b.Write(m.value)
+}
+
+// close gives back the object.
+func (w *memberWriter) close() []byte {
+	if w.b.Len() == 0 {
+		w.b.WriteByte('{')
+	}
+	w.b.WriteByte('}')
+	return w.b.Bytes()
+}
+
+func errUnwindNotObject(t string, w json.Where) error {
+	return &json.TypeMismatchError{
+		Where:    w,
+		Expected: "object",
+		Found:    t,
+		Err:      fmt.Errorf("-%s needs each record to be an object but found %s", options.OptUnwind, t),
+	}
+}